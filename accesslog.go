@@ -0,0 +1,59 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// accessLogTimeFormat is the request time format of the combined log format
+const accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// writeAccessLog is to write the request in the combined log format into AccessLogWriter, e.g:
+// 127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.1" 200 2326 "http://example.com/" "Mozilla/5.0"
+func (i *IngressLog) writeAccessLog(config *Config, request *LogRequest, response *LogResponse, requestTimestamp time.Time) {
+	if config.URLSanitizer != nil || len(config.SensitiveQueryKeys) > 0 {
+		sanitized := *request
+		sanitized.URL = config.redactQueryString(config.sanitizeURL(request.URL))
+		request = &sanitized
+	}
+	line := config.formatAccessLog(request, response, requestTimestamp)
+
+	// write the whole line at once, so the lines of concurrent requests are not interleaved
+	i.accessLogMu.Lock()
+	defer i.accessLogMu.Unlock()
+	config.AccessLogWriter.Write(line)
+}
+
+func (c *Config) formatAccessLog(request *LogRequest, response *LogResponse, requestTimestamp time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(c.getClientIP(request))
+	buf.WriteString(" - - [")
+	buf.WriteString(requestTimestamp.Format(accessLogTimeFormat))
+	buf.WriteString("] ")
+	buf.WriteString(strconv.Quote(request.Method + " " + request.URL + " " + request.Proto))
+	buf.WriteString(" ")
+	buf.WriteString(strconv.Itoa(response.Status))
+	buf.WriteString(" ")
+	if response.BodySize > 0 {
+		buf.WriteString(strconv.Itoa(response.BodySize))
+	} else {
+		buf.WriteString(wipedMessage)
+	}
+	buf.WriteString(" ")
+	buf.WriteString(quoteAccessLogValue(request.Header.Get(headerNameReferer)))
+	buf.WriteString(" ")
+	buf.WriteString(quoteAccessLogValue(request.Header.Get(headerNameUserAgent)))
+	buf.WriteString("\n")
+
+	return buf.Bytes()
+}
+
+// quoteAccessLogValue is to quote the header value, empty value is logged as "-"
+func quoteAccessLogValue(value string) string {
+	if len(value) == 0 {
+		return `"-"`
+	}
+
+	return strconv.Quote(value)
+}
@@ -0,0 +1,61 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestFormatAccessLog(t *testing.T) {
+	request := &LogRequest{
+		URL:        "/users?page=1",
+		Method:     http.MethodGet,
+		Proto:      "HTTP/1.1",
+		RemoteAddr: "10.0.0.1:51234",
+		Header: http.Header{
+			"Referer":    []string{"http://example.com/"},
+			"User-Agent": []string{`curl/7.68.0 "test"`},
+		},
+	}
+	requestTimestamp := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60))
+
+	config := NewConfig(&Config{})
+	line := config.formatAccessLog(request, &LogResponse{Status: http.StatusOK, BodySize: 2326}, requestTimestamp)
+	assert.Equal(t,
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /users?page=1 HTTP/1.1" 200 2326 "http://example.com/" "curl/7.68.0 \"test\""`+"\n",
+		string(line))
+
+	request.Header = make(http.Header)
+	line = config.formatAccessLog(request, &LogResponse{Status: http.StatusNoContent}, requestTimestamp)
+	assert.Equal(t, `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /users?page=1 HTTP/1.1" 204 - "-" "-"`+"\n", string(line))
+}
+
+func TestLogIngressAccessLogWriter(t *testing.T) {
+	var accessLog bytes.Buffer
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{AccessLogWriter: &accessLog})
+
+	middleware.Enforce(http.HandlerFunc(echoHandler)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+
+	assert.Contains(t, accessLog.String(), `"POST /echo HTTP/1.1" 200 5 "-" "-"`)
+	assert.Equal(t, 1, strings.Count(accessLog.String(), "\n"))
+	assert.Equal(t, 1, len(hook.AllEntries()))
+}
+
+func TestLogIngressAccessLogSensitiveQueryKeys(t *testing.T) {
+	var accessLog bytes.Buffer
+	logger, _ := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{AccessLogWriter: &accessLog, SensitiveQueryKeys: []string{"token", "api_key"}})
+
+	middleware.Enforce(http.HandlerFunc(echoHandler)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/echo?page=1&Token=secret&api%5Fkey=abc&q=a%20b", nil))
+
+	assert.Contains(t, accessLog.String(), `"GET /echo?page=1&Token=-&api%5Fkey=-&q=a%20b HTTP/1.1"`)
+}
@@ -0,0 +1,141 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+const valueEncodingBase64 = "base64"
+
+// BodyFormat controls how the logged JSON request/response body is formatted
+type BodyFormat int
+
+const (
+	BodyFormatRaw         BodyFormat = iota // log the body as received
+	BodyFormatCompactJSON                   // strip the insignificant whitespace of JSON body
+	BodyFormatPrettyJSON                    // indent JSON body
+)
+
+// formatBody is to prepare the request/response body to be logged based on the config
+func (c *Config) formatBody(contentType string, body string) string {
+	if len(body) == 0 {
+		return c.EmptyBodyPlaceholder
+	}
+
+	if !c.IsLoggableContentType(contentType) {
+		return wipedMessage
+	}
+
+	body = c.maskBody(contentType, body)
+	body = c.redactBodyPart(contentType, body)
+
+	body = formatJSONBody(contentType, body, c.BodyFormat)
+
+	if c.HeadTailBytes > 0 && !strings.HasSuffix(body, truncatedMessage) {
+		// the tail of the truncated body is unknown here, see formatHeadTailBody
+		return headTailBody(body, c.HeadTailBytes)
+	}
+
+	return truncateBody(body, c.MaxBodyBytes)
+}
+
+// formatBinaryBody is to base64 encode the body which is not valid UTF-8 when Base64BinaryBodies is true,
+// ok is false for the text body, which is formatted as is
+func (c *Config) formatBinaryBody(contentType string, body string) (string, bool) {
+	if !c.Base64BinaryBodies || utf8.ValidString(body) {
+		return "", false
+	}
+
+	if !c.IsLoggableContentType(contentType) {
+		return wipedMessage, true
+	}
+
+	return truncateBody(base64.StdEncoding.EncodeToString([]byte(body)), c.MaxBodyBytes), true
+}
+
+// formatNonTextBody is to replace the request body whose content type is neither text nor JSON when TextBodiesOnly
+// is true, ok is false for the text body, which is formatted as is. The multipart form is logged as its summary
+func (c *Config) formatNonTextBody(contentType string, request *LogRequest) (string, bool) {
+	if !c.TextBodiesOnly || request.BodySize == 0 || isTextContentType(contentType) {
+		return "", false
+	}
+
+	if isMultipartFormData(contentType) && !strings.HasSuffix(request.Body, truncatedMessage) {
+		// the completely captured form is summarized into its field and file names
+		return "", false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentTypeOctetStream
+	}
+
+	return fmt.Sprintf(binaryBodyMessage, mediaType, request.BodySize), true
+}
+
+// isTextContentType is to check whether the body of the content type is text, i.e: text/*, JSON, XML or form
+func isTextContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"), isJSONContentType(contentType):
+		return true
+	case mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"):
+		return true
+	default:
+		return mediaType == "application/x-www-form-urlencoded"
+	}
+}
+
+// formatJSONBody is to compact or indent JSON body, non-JSON or invalid JSON body is returned as is
+func formatJSONBody(contentType string, body string, format BodyFormat) string {
+	if format == BodyFormatRaw || body == "" || !isJSONContentType(contentType) {
+		return body
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case BodyFormatCompactJSON:
+		err = json.Compact(&buf, []byte(body))
+	case BodyFormatPrettyJSON:
+		err = json.Indent(&buf, []byte(body), "", "  ")
+	default:
+		return body
+	}
+	if err != nil {
+		return body
+	}
+
+	return buf.String()
+}
+
+// truncateBody is to cut the logged body to maxBytes, 0 means no limit
+func truncateBody(body string, maxBytes int) string {
+	if maxBytes <= 0 || len(body) <= maxBytes {
+		return body
+	}
+	if captured := strings.TrimSuffix(body, truncatedMessage); len(captured) < len(body) && len(captured) <= maxBytes {
+		// the captured body is already cut, e.g: the redacted body shorter than the capture limit
+		return body
+	}
+
+	return body[:maxBytes] + truncatedMessage
+}
+
+// headTailBody is to keep the first and last n bytes of the body longer than 2n, e.g: "[{...[120 bytes omitted]...}]"
+func headTailBody(body string, n int) string {
+	if len(body) <= 2*n {
+		return body
+	}
+
+	return body[:n] + fmt.Sprintf(omittedMessage, len(body)-2*n) + body[len(body)-n:]
+}
@@ -0,0 +1,48 @@
+package httpmiddleware
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestConfigFormatBodyRedactor(t *testing.T) {
+	pinPattern := regexp.MustCompile(`<pin>\d+</pin>`)
+	config := NewConfig(&Config{
+		MaxBodyBytes: 40,
+		BodyRedactor: func(contentType string, body []byte) []byte {
+			if contentType != "application/xml" {
+				return body
+			}
+			return pinPattern.ReplaceAll(body, []byte("<pin>-</pin>"))
+		},
+	})
+
+	body := "<user><name>shopee</name><pin>123456</pin></user>"
+	assert.Equal(t, "<user><name>shopee</name><pin>-</pin></u"+truncatedMessage, config.formatBody("application/xml", body))
+	assert.Equal(t, "<user><name>shopee</name><pin>123456</pi"+truncatedMessage, config.formatBody("text/plain", body))
+}
+
+func TestConfigFormatBodyFormat(t *testing.T) {
+	body := "{\n  \"name\": \"shopee\",\n  \"tags\": [1, 2]\n}"
+
+	config := NewConfig(&Config{BodyFormat: BodyFormatCompactJSON})
+	assert.Equal(t, `{"name":"shopee","tags":[1,2]}`, config.formatBody("application/json", body))
+	assert.Equal(t, body, config.formatBody("text/plain", body))
+	assert.Equal(t, "{invalid", config.formatBody("application/json", "{invalid"))
+
+	config = NewConfig(&Config{BodyFormat: BodyFormatPrettyJSON})
+	assert.Equal(t, "{\n  \"name\": \"shopee\"\n}", config.formatBody("application/json", `{"name":"shopee"}`))
+
+	config = NewConfig(&Config{})
+	assert.Equal(t, body, config.formatBody("application/json", body))
+}
+
+func TestConfigFormatBodyEmptyBodyPlaceholder(t *testing.T) {
+	assert.Equal(t, "", NewConfig(&Config{}).formatBody("application/json", ""))
+
+	config := NewConfig(&Config{EmptyBodyPlaceholder: "<empty>", LoggableContentTypes: []string{"application/json"}})
+	assert.Equal(t, "<empty>", config.formatBody("", ""))
+	assert.Equal(t, "null", config.formatBody("application/json", "null"))
+}
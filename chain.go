@@ -0,0 +1,15 @@
+package httpmiddleware
+
+import "net/http"
+
+// Chain is to compose the middlewares into one, the first middleware is the outermost, e.g:
+// Chain(recoverMiddleware, ingressLog.Enforce, authMiddleware)(handler) handles the request in the given order
+func Chain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+
+		return next
+	}
+}
@@ -0,0 +1,39 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	middleware := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+":before")
+				next.ServeHTTP(w, r)
+				calls = append(calls, name+":after")
+			})
+		}
+	}
+
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	handler := Chain(middleware("first"), NewIngressLogMiddleware(logger).Enforce, middleware("second"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+			w.WriteHeader(http.StatusAccepted)
+		}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	assert.Equal(t, []string{"first:before", "second:before", "handler", "second:after", "first:after"}, calls)
+	assert.Equal(t, http.StatusAccepted, hook.LastEntry().Data[FieldStatus])
+
+	// no middleware returns the handler as is
+	recorder := httptest.NewRecorder()
+	Chain()(http.HandlerFunc(echoHandler)).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/echo", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
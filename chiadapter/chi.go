@@ -0,0 +1,27 @@
+// Package chiadapter provides the ingress log middleware for github.com/go-chi/chi
+package chiadapter
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muhammad-fakhri/httpmiddleware"
+)
+
+// Enforce is to apply log ingress middleware to the chi router, e.g: router.Use(chiadapter.Enforce(ingressLog)).
+// The matched route pattern e.g: "/users/{id}" is logged as the route
+func Enforce(ingressLog *httpmiddleware.IngressLog) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return ingressLog.EnforceWithRouteFunc(routePattern, next)
+	}
+}
+
+// routePattern is to get the route pattern matched by chi, it is complete only after the handler returns
+func routePattern(r *http.Request) string {
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext == nil {
+		return ""
+	}
+
+	return routeContext.RoutePattern()
+}
@@ -0,0 +1,48 @@
+package chiadapter
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/go-chi/chi/v5"
+	"github.com/muhammad-fakhri/httpmiddleware"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestEnforce(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+
+	router := chi.NewRouter()
+	router.Use(Enforce(httpmiddleware.NewIngressLogMiddleware(logger)))
+	router.Route("/users", func(r chi.Router) {
+		r.Post("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(chi.URLParam(r, "id")))
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/123", bytes.NewReader([]byte(`{"name":"shopee"}`)))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "123", recorder.Body.String())
+
+	entry := hook.LastEntry()
+	assert.Equal(t, "/users/{id}", entry.Data[httpmiddleware.FieldRoute])
+	assert.Equal(t, "/users/123", entry.Data[httpmiddleware.FieldPath])
+	assert.Equal(t, http.StatusCreated, entry.Data[httpmiddleware.FieldStatus])
+	assert.Equal(t, `{"name":"shopee"}`, entry.Data[httpmiddleware.FieldReqBody])
+	assert.Equal(t, "123", entry.Data[httpmiddleware.FieldResponseBody])
+
+	req = httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, hook.LastEntry().Data[httpmiddleware.FieldStatus])
+	_, ok := hook.LastEntry().Data[httpmiddleware.FieldRoute]
+	assert.False(t, ok)
+}
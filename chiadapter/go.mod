@@ -0,0 +1,22 @@
+module github.com/muhammad-fakhri/httpmiddleware/chiadapter
+
+go 1.17
+
+require (
+	github.com/c2fo/testify v0.0.0-20150827203832-fba96363964a
+	github.com/go-chi/chi/v5 v5.0.8
+	github.com/muhammad-fakhri/httpmiddleware v0.0.0-00010101000000-000000000000
+	github.com/muhammad-fakhri/log v1.0.2
+)
+
+require (
+	github.com/google/uuid v1.1.1 // indirect
+	github.com/julienschmidt/httprouter v1.3.0 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/sirupsen/logrus v1.4.2 // indirect
+	go.opentelemetry.io/otel v1.10.0 // indirect
+	go.opentelemetry.io/otel/trace v1.10.0 // indirect
+	golang.org/x/sys v0.0.0-20220227234510-4e6760a101f9 // indirect
+)
+
+replace github.com/muhammad-fakhri/httpmiddleware => ../
@@ -0,0 +1,76 @@
+package httpmiddleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// parseTrustedProxies is to parse the trusted proxy CIDRs, a single ip is a network of its own
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("httpmiddleware: invalid trusted proxy %q", proxy)
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// isTrustedProxy is to check whether the ip belongs to TrustedProxies
+func (c *Config) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, network := range c.trustedProxyNets {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getClientIP is to get the originating client ip. The X-Forwarded-For and X-Real-IP headers are only used when
+// the request comes from a trusted proxy, otherwise any client could forge them. The X-Forwarded-For chain is
+// walked from the right, the first ip not in TrustedProxies is the client
+func (c *Config) getClientIP(request *LogRequest) string {
+	remoteIP, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		remoteIP = request.RemoteAddr
+	}
+
+	if !c.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	if forwardedFor := request.Header.Values(headerNameForwardedFor); len(forwardedFor) > 0 {
+		chain := strings.Split(strings.Join(forwardedFor, ","), ",")
+		for idx := len(chain) - 1; idx >= 0; idx-- {
+			if ip := strings.TrimSpace(chain[idx]); !c.isTrustedProxy(ip) || idx == 0 {
+				return ip
+			}
+		}
+	}
+
+	if realIP := request.Header.Get(headerNameRealIP); realIP != "" {
+		return strings.TrimSpace(realIP)
+	}
+
+	return remoteIP
+}
@@ -0,0 +1,60 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// decodeBody is to decompress the body based on its content encoding, the body is returned
+// as it is when the encoding is not supported or the decompression fails. Only up to limit bytes are
+// decompressed, so a highly compressed body can not exhaust the memory, truncated is true when the
+// decompressed body is longer than the limit. 0 means no limit
+func decodeBody(contentEncoding string, body []byte, limit int) (decoded []byte, truncated bool) {
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case encodingGzip:
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case encodingDeflate:
+		// deflate content encoding is zlib wrapped, but some clients send raw deflate stream
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return body, false
+	}
+	if err != nil {
+		return body, false
+	}
+	defer reader.Close()
+
+	// one more byte than the limit is read to tell whether the decompressed body is longer than the limit
+	readLimit := int64(limit) + 1
+	if limit <= 0 || readLimit <= 0 {
+		readLimit = math.MaxInt64
+	}
+	decoded, err = ioutil.ReadAll(io.LimitReader(reader, readLimit))
+	if err != nil {
+		return body, false
+	}
+
+	if limit > 0 && len(decoded) > limit {
+		return decoded[:limit], true
+	}
+	return decoded, false
+}
@@ -0,0 +1,152 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func compress(t *testing.T, encoding string, body string) []byte {
+	var (
+		buf    bytes.Buffer
+		writer io.WriteCloser
+	)
+
+	switch encoding {
+	case encodingGzip:
+		writer = gzip.NewWriter(&buf)
+	case encodingDeflate:
+		writer = zlib.NewWriter(&buf)
+	default:
+		writer, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+
+	if _, err := writer.Write([]byte(body)); err != nil {
+		t.Error(err)
+	}
+	writer.Close()
+
+	return buf.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	body := `{"name":"shopee"}`
+
+	for _, tt := range []struct {
+		encoding string
+		body     []byte
+		expected string
+	}{
+		{encoding: "gzip", body: compress(t, encodingGzip, body), expected: body},
+		{encoding: "Deflate", body: compress(t, encodingDeflate, body), expected: body},
+		{encoding: "deflate", body: compress(t, "raw", body), expected: body},
+		{encoding: "br", body: []byte(body), expected: body},
+		{encoding: "gzip", body: []byte("not gzip"), expected: "not gzip"},
+	} {
+		decoded, truncated := decodeBody(tt.encoding, tt.body, defaultMaxBodyCaptureBytes)
+		assert.Equal(t, tt.expected, string(decoded))
+		assert.False(t, truncated)
+	}
+}
+
+func TestDecodeBodyLimit(t *testing.T) {
+	// a highly compressible body is decompressed only up to the limit
+	compressedBody := compress(t, encodingGzip, strings.Repeat("a", 10<<20))
+
+	decoded, truncated := decodeBody(encodingGzip, compressedBody, 16)
+	assert.Equal(t, strings.Repeat("a", 16), string(decoded))
+	assert.True(t, truncated)
+}
+
+func TestLogIngressMessageDecodeCompressedRequestBody(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{DecodeCompressedBody: true})
+	defer mockServer.Close()
+
+	reqBody := `{"name":"shopee"}`
+	compressedBody := compress(t, encodingGzip, reqBody)
+
+	req, _ := http.NewRequest(http.MethodPost, mockServer.URL+"/echo", bytes.NewReader(compressedBody))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	client := &http.Client{}
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	logMessage := extractLogMessage(t, hook.LastEntry().Data)
+	assert.Equal(t, reqBody, logMessage.ReqBody)
+	// handler receives the original compressed body
+	assert.Equal(t, string(compressedBody), logMessage.ResponseBody)
+	assert.Equal(t, len(compressedBody), hook.LastEntry().Data[FieldReqSize])
+}
+
+func TestLogIngressMessageDecodeCompressedRequestBodyLimit(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{DecodeCompressedBody: true, MaxBodyBytes: 4096})
+
+	// the compressed body is captured completely, but only decompressed up to MaxBodyBytes
+	compressedBody := compress(t, encodingGzip, strings.Repeat("a", 1<<20))
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(compressedBody))
+	req.Header.Set("Content-Encoding", "gzip")
+	middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, strings.Repeat("a", 4096)+truncatedMessage, hook.LastEntry().Data[FieldReqBody])
+	assert.Equal(t, len(compressedBody), hook.LastEntry().Data[FieldReqSize])
+}
+
+func TestLogIngressMessageDecodeCompressedResponseBody(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{DecodeCompressedBody: true})
+
+	rspBody := `{"name":"shopee"}`
+	compressedBody := compress(t, encodingGzip, rspBody)
+	handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressedBody)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	// client receives the original compressed body
+	assert.Equal(t, compressedBody, recorder.Body.Bytes())
+
+	logMessage := extractLogMessage(t, hook.LastEntry().Data)
+	assert.Equal(t, rspBody, logMessage.ResponseBody)
+	assert.Equal(t, len(compressedBody), hook.LastEntry().Data[FieldResponseSize])
+}
+
+func TestLogIngressMessageDecodeCompressedResponseBodyLimit(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{DecodeCompressedBody: true, MaxBodyBytes: 4096})
+
+	// the compressed body is captured completely, but only decompressed up to MaxBodyBytes
+	compressedBody := compress(t, encodingGzip, strings.Repeat("a", 1<<20))
+	handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressedBody)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/download", nil))
+
+	assert.Equal(t, compressedBody, recorder.Body.Bytes())
+	assert.Equal(t, strings.Repeat("a", 4096)+truncatedMessage, hook.LastEntry().Data[FieldResponseBody])
+	assert.Equal(t, len(compressedBody), hook.LastEntry().Data[FieldResponseSize])
+}
@@ -1,9 +1,240 @@
 package httpmiddleware
 
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muhammad-fakhri/log"
+)
+
 type Config struct {
 	ExcludeOpt        *ExcludeOption
 	DisableIngressLog bool // true: add important info to context and disable default ingress log (usecase: custom logging implementation), default value: false
 	FieldOpt          *FieldOption
+	// MaxBodyBytes is the maximum length of the captured and logged request/response body, longer body will be truncated,
+	// the handler and the client still get the whole body, default value: 0 (the body is captured up to 1MB)
+	MaxBodyBytes int
+	// SensitiveHeaderKeys are stripped from the logged request and response header, default value: ["Authorization"].
+	// Set it to an empty non-nil slice to keep every header
+	SensitiveHeaderKeys []string
+	// SkipPaths are the request paths served without ingress log, the path is matched exactly
+	// or by prefix when it ends with "*", e.g: "/healthz", "/metrics/*"
+	SkipPaths []string
+	// StatusBasedLogLevel true: log 5xx response as error and 4xx response as warning, default value: false (all info)
+	StatusBasedLogLevel bool
+	// DecodeCompressedBody true: decompress gzip/deflate encoded request/response body for the log only, up to the
+	// captured size limit (MaxBodyBytes or 1MB), default value: false
+	DecodeCompressedBody bool
+	// LoggableContentTypes are the content type prefixes whose body is logged, e.g: "application/json", "text/".
+	// Body with other content type is replaced by "-", default value: empty (log every content type)
+	LoggableContentTypes []string
+	// RouteConfig overrides the config used to build the log entry of the matching request path, the longest matching
+	// path prefix wins, e.g: "/upload", "/api/v1/*". Only the non-zero fields of the route config, including the ones of
+	// its ExcludeOpt, override the base config, so the base masking and redaction still apply to the route. A flag
+	// enabled by the base config can not be disabled by the route config
+	RouteConfig map[string]*Config
+	// LogOnRequestStart true: emit an additional log entry before the request is handled, default value: false
+	LogOnRequestStart bool
+	// SlowRequestThresholdMs marks request taking longer than the threshold as slow, default value: 0 (disabled)
+	SlowRequestThresholdMs int64
+	// SlowRequestWarn true: log slow request at least as warning, default value: false
+	SlowRequestWarn bool
+	// LogQueryParams true: log the query params as a separate field and omit the query string from the url, default value: false
+	LogQueryParams bool
+	// SensitiveQueryKeys are the query params whose value is redacted, applied when LogQueryParams is true and
+	// to the url of the access log line
+	SensitiveQueryKeys []string
+	// LogTraceContext true: log the OpenTelemetry trace and span id from the request context, default value: false
+	LogTraceContext bool
+	// RequestIDHeaders are the candidate headers of the incoming request id, checked in order, default value: ["x-request-id"]
+	RequestIDHeaders []string
+	// EchoRequestIDHeader true: write the request context id into the X-Request-ID response header, default value: false
+	EchoRequestIDHeader bool
+	// SuccessSampleRate is the fraction (0.0-1.0) of 2xx requests to be logged, sampled deterministically by the context id.
+	// Non-2xx requests are always logged. 0 or less means unset, use ExcludeOption.SuccessRequest or LogStatusPredicate
+	// to drop every 2xx request instead, default value: 0 (log every request, same as 1.0)
+	SuccessSampleRate float64
+	// ContextFields is called with the request context to add application specific fields into the log,
+	// the built-in fields are not overridden
+	ContextFields func(ctx context.Context) map[string]interface{}
+	// BodyRedactor is called with the content type and the request/response body before it is logged,
+	// e.g: for XML or regex based redaction. It only changes the logged copy, not the body seen by the handler or client
+	BodyRedactor func(contentType string, body []byte) []byte
+	// RequestHeaderAllowlist are the only request header keys to be logged when set,
+	// it takes precedence over ExcludeOption.RequestHeaderKeys, the SensitiveHeaderKeys are still stripped
+	RequestHeaderAllowlist []string
+	// ResponseHeaderAllowlist are the only response header keys to be logged when set, the SensitiveHeaderKeys are still stripped
+	ResponseHeaderAllowlist []string
+	// ErrorContextKey is the request context key of the handler error to be logged. The middleware stores
+	// an *error under the key when it is absent, so the handler can assign it, e.g: *ctx.Value(key).(*error) = err
+	ErrorContextKey interface{}
+	// RouteNameExtractor is called after the handler returns to get the route name logged as FieldRoute,
+	// e.g: func(r *http.Request) string { return mux.CurrentRoute(r).GetName() } for gorilla/mux
+	RouteNameExtractor func(r *http.Request) string
+	// PanicResponse builds the response written when the handler panics, default value: nil (500 with plaintext "panic: <value>.")
+	PanicResponse func(recovered interface{}) (status int, contentType string, body []byte)
+	// RePanicAfterLog true: re-raise the recovered panic after it is logged with status 500, so the outer
+	// middleware can handle it, no panic response is written, default value: false
+	RePanicAfterLog bool
+	LogUserAgent    bool // true: log the User-Agent header as a separate field, even when the request header is excluded
+	LogReferer      bool // true: log the Referer header as a separate field, even when the request header is excluded
+	// LogStatusPredicate decides whether the request is logged based on the response status, default value: nil (log every status)
+	LogStatusPredicate func(status int) bool
+	// DisableCombinedURL true: omit the deprecated combined "METHOD URL" field, FieldMethod and FieldPath are logged instead
+	DisableCombinedURL bool
+	// HandlerTimeout is the maximum duration of the handler, set as the request context deadline. The request
+	// is responded with 503 and logged as timed out when the handler exceeds it, default value: 0 (no timeout)
+	HandlerTimeout time.Duration
+	// BodyFormat controls how the JSON request/response body is logged, non-JSON body is always logged as is,
+	// default value: BodyFormatRaw
+	BodyFormat BodyFormat
+	// StreamingPaths are the request paths whose response is streamed, e.g: Server-Sent Events. The response body
+	// is passed through without being buffered or logged, and HandlerTimeout is not applied. Matched like SkipPaths.
+	// Response with "text/event-stream" content type is always streamed, but its path must be listed to bypass HandlerTimeout
+	StreamingPaths []string
+	// AfterLog is called with the request log message after the ingress log is emitted, e.g: to record metrics.
+	// It is called for every request, including the ones whose log is skipped or sampled out
+	AfterLog func(ctx context.Context, msg *LogMessage)
+	// IDGenerator generates the context id of the request without an incoming request id, default value: nil (UUID v4)
+	IDGenerator func() string
+	// HashOversizedBody true: log the request/response body longer than MaxBodyBytes as its SHA-256 digest
+	// and length e.g: "sha256:<hex>;len=12345" instead of truncating it. The body is hashed as it streams through,
+	// the request body not read to the end by the handler is logged as "-", default value: false
+	HashOversizedBody bool
+	// TimestampFormat controls how the request timestamp is logged, default value: TimestampFormatUnix
+	TimestampFormat TimestampFormat
+	// LogTLSInfo true: log the TLS version and cipher suite of HTTPS request, default value: false
+	LogTLSInfo bool
+	// LogPanicStack true: log the stack trace of the recovered handler panic, default value: false
+	LogPanicStack bool
+	// NoBodyLogPathPatterns are the regular expressions of the request paths whose request and response body
+	// are replaced by "-", e.g: "^/internal/debug/". They are compiled by NewConfig, which panics on invalid pattern
+	NoBodyLogPathPatterns []string
+	// AccessLogWriter receives a combined log format line of every handled request in addition to the ingress log,
+	// e.g: for a legacy access log tool, default value: nil (disabled)
+	AccessLogWriter io.Writer
+	// ParseFormBody true: log application/x-www-form-urlencoded request body as a JSON object of the form values,
+	// the values of SensitiveQueryKeys are redacted, default value: false
+	ParseFormBody bool
+	// AuditLogger additionally receives the ingress log of the requests matching AuditPredicate, regardless of
+	// the sampling and skipping of the ingress log, default value: nil (disabled)
+	AuditLogger log.Logger
+	// AuditPredicate decides whether the request is logged to AuditLogger, default value: nil (every request)
+	AuditPredicate func(msg *LogMessage) bool
+	// MaxLoggedResponseBodyBytes replaces the response body longer than it with "body too large (<size> bytes)"
+	// instead of truncating it, default value: 0 (disabled)
+	MaxLoggedResponseBodyBytes int
+	// DurationUnit controls the unit of the logged request duration, default value: DurationUnitMillis (FieldDurationMs)
+	DurationUnit DurationUnit
+	// LogResponseTrailers true: log the response trailers as FieldResponseTrailer and the request trailers
+	// as FieldReqTrailer, e.g: "Grpc-Status", default value: false
+	LogResponseTrailers bool
+	// RedactPatterns are the regexes of the values replaced by "-" anywhere in the logged request/response body,
+	// e.g: []string{RedactPatternPAN, RedactPatternEmail}, compiled once by NewConfig. The digits where the body is
+	// cut by the capture limit are wiped too, default value: nil
+	RedactPatterns []string
+	// EventNameFunc names the business action of the request, logged as FieldEvent prefixed by GetEventPrefix,
+	// e.g: "user.login" for "POST /login" is logged as "events/user.login", default value: nil
+	EventNameFunc func(*LogRequest) string
+	// HeadTailBytes logs only the first and last N bytes of the request/response body longer than 2*N
+	// instead of truncating it to MaxBodyBytes, e.g: to keep the trailing error of a JSON array. The tail of the body
+	// longer than the capture limit is kept as it streams through, the request body not read to the end by the handler
+	// is still truncated, default value: 0 (disabled)
+	HeadTailBytes int
+	// SkipMethods are the request methods not logged, matched case-insensitively, e.g: "OPTIONS" for the CORS preflight,
+	// default value: empty (log every method)
+	SkipMethods []string
+	// EmptyBodyPlaceholder is logged for the absent or empty request/response body, distinguishing it from
+	// a body literally equal to "null", e.g: "-", default value: "" (empty string)
+	EmptyBodyPlaceholder string
+	// MaxHeaderValueLength truncates each logged request/response header value to N bytes with "..." suffix,
+	// the handler still receives the full value, default value: 0 (no limit)
+	MaxHeaderValueLength int
+	// BodySampleRate is the fraction of requests, in (0, 1), whose request/response bodies are logged, decided
+	// deterministically by the context id. The bodies of other requests are replaced by "-" while the rest of
+	// the entry is still logged, default value: 0 (log every body)
+	BodySampleRate float64
+	// Base64BinaryBodies true: log the request body which is not valid UTF-8 base64 encoded, marked by
+	// FieldReqBodyEncoding "base64", e.g: for the binary webhook payload, default value: false
+	Base64BinaryBodies bool
+	// AlwaysLogStatuses are the response statuses logged in full regardless of SuccessSampleRate and BodySampleRate,
+	// default value: nil (every 5xx status), set an empty slice to sample every status
+	AlwaysLogStatuses []int
+	// LogRouteParams true: log the route params of EnforceWithParams and EnforceWithRoute as FieldRouteParams,
+	// e.g: {"id": "123"}, default value: false
+	LogRouteParams bool
+	// SensitiveRouteParams are the route params whose value is redacted, only applied when LogRouteParams is true
+	SensitiveRouteParams []string
+	// DowngradeStatuses are the response statuses logged as info even when StatusBasedLogLevel is true,
+	// e.g: 401 and 404 of a public API, default value: empty
+	DowngradeStatuses []int
+	// ServerID identifies the instance serving the request, logged as FieldServerID in every entry,
+	// default value: "" (the hostname, resolved once by NewIngressLogMiddleware and SetConfig)
+	ServerID string
+	// CommonFields are the static fields added to every entry, e.g: {"service": "checkout", "env": "prod"},
+	// the per-request fields are not overridden, default value: nil
+	CommonFields map[string]interface{}
+	// FlattenHeaders true: log the request/response header as a JSON object of string for the single-valued keys
+	// and []string for the multi-valued keys, instead of http.Header, default value: false
+	FlattenHeaders bool
+	// LogBodySizeRatio true: log the response body size divided by the request body size as FieldBodySizeRatio
+	// when both are non-empty, e.g: to detect response amplification, default value: false
+	LogBodySizeRatio bool
+	// URLSanitizer rewrites the logged request path, e.g: to replace the token segment of "/reset-password/<token>/confirm"
+	// with "*". The path is still matched against the config as it is, default value: nil (log the path as it is)
+	URLSanitizer func(path string) string
+	// MaxConcurrentBodyCapture bounds the requests capturing their request/response bodies at the same time,
+	// the bodies of the other requests are logged as "-" while the rest of the entry is still logged,
+	// default value: 0 (no limit)
+	MaxConcurrentBodyCapture int
+	// LogSink receives the fields of the ingress log entries instead of the logger, e.g: for a custom log transport.
+	// The context data such as the context id is not included, see GetContextID, default value: nil (use the logger)
+	LogSink func(ctx context.Context, fields map[string]interface{})
+	// LogHostScheme true: log the request host as FieldHost and the scheme, inferred from X-Forwarded-Proto header
+	// or the TLS connection, as FieldScheme, default value: false
+	LogHostScheme bool
+	// MaxLogBytes bounds the estimated JSON size of the ingress log entry by dropping the bodies, headers, then
+	// the other optional fields, listed in FieldTruncatedFields. The status, duration and url are always logged,
+	// default value: 0 (no limit)
+	MaxLogBytes int
+	// LogTraceParent true: log the trace id and parent span id of the W3C traceparent request header as FieldTraceID
+	// and FieldParentSpanID, without the OpenTelemetry SDK, default value: false
+	LogTraceParent bool
+	// TextBodiesOnly true: log the request body only when the Content-Type is text or JSON, e.g: "text/plain",
+	// "application/json". Other bodies are logged as "<binary image/png body, 1024 bytes>", default value: false
+	TextBodiesOnly bool
+	// LogRequestFingerprint true: log the SHA-1 hex digest of the request method, path, FingerprintHeaders and body
+	// as FieldRequestFingerprint, e.g: to group the duplicate requests. It is not logged when the body is included
+	// but not completely captured, e.g: the request body is not logged or longer than MaxBodyBytes, default value: false
+	LogRequestFingerprint bool
+	// FingerprintHeaders are the request headers included in the request fingerprint, default value: nil (no header)
+	FingerprintHeaders []string
+	// FingerprintExcludeBody true: leave the request body out of the request fingerprint, so it is logged for every
+	// request, default value: false
+	FingerprintExcludeBody bool
+	// DisableEgressLog true: disable the outbound request log of EgressLog, the context id is still propagated into
+	// the request id header, default value: false
+	DisableEgressLog bool
+	// ExcludeResponseBodyStatuses are the response statuses whose body is replaced by "-", e.g: 204, 304, 401, 403,
+	// default value: nil
+	ExcludeResponseBodyStatuses []int
+	// TrustedProxies are the proxy ips or CIDRs, e.g: "10.0.0.0/8", whose X-Forwarded-For and X-Real-IP headers are
+	// used to get the logged client ip, default value: empty (the client ip is the connection remote address)
+	TrustedProxies []string
+
+	noBodyLogPathRegexps []*regexp.Regexp
+	redactRegexps        []*regexp.Regexp
+	serverID             string
+	bodyCaptureSem       chan struct{}      // bounds the concurrent body capture, nil when there is no limit
+	routeConfigs         map[string]*Config // RouteConfig merged over the config, see GetRouteConfig
+	trustedProxyNets     []*net.IPNet
 }
 
 type ExcludeOption struct {
@@ -12,12 +243,19 @@ type ExcludeOption struct {
 	ResponseHeader      bool
 	ResponseBody        bool
 	SuccessResponseBody bool
+	SuccessRequestBody  bool // true: replace the request body of 2xx response with "-", the body of the other responses is still logged
 	SuccessRequest      bool
 	RequestHeaderKeys   []string
+	MaskBodyFields      []string // JSON body fields to be masked, including the nested ones
+	MaskValue           string   // value to replace the masked fields with, default value: "-"
+	ClientIP            bool
 }
 
 type FieldOption struct {
 	EventPrefix string
+	// FieldNames maps the default field names to the logged ones, e.g: {"status": "http.status_code"},
+	// unmapped fields keep their default name
+	FieldNames map[string]string
 }
 
 func defaultConfig() *Config {
@@ -26,12 +264,93 @@ func defaultConfig() *Config {
 	}
 }
 
+// NewConfig is to prepare the config to be used, an invalid pattern of NoBodyLogPathPatterns or RedactPatterns panics
 func NewConfig(c *Config) *Config {
+	if err := c.prepare(); err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// prepare is to set the defaults and compile the patterns of the config and its route configs
+func (c *Config) prepare() error {
 	if c.ExcludeOpt == nil {
 		c.ExcludeOpt = &ExcludeOption{}
 	}
 
-	return c
+	var err error
+	if c.noBodyLogPathRegexps, err = compilePatterns(c.NoBodyLogPathPatterns); err != nil {
+		return err
+	}
+	if c.redactRegexps, err = compilePatterns(c.RedactPatterns); err != nil {
+		return err
+	}
+	if c.trustedProxyNets, err = parseTrustedProxies(c.TrustedProxies); err != nil {
+		return err
+	}
+
+	c.routeConfigs = make(map[string]*Config, len(c.RouteConfig))
+	for prefix, routeConfig := range c.RouteConfig {
+		if routeConfig == nil {
+			continue
+		}
+
+		merged := c.mergeRouteConfig(routeConfig)
+		if err = merged.prepare(); err != nil {
+			return err
+		}
+		c.routeConfigs[prefix] = merged
+	}
+
+	return nil
+}
+
+// mergeRouteConfig is to get the effective config of the route, the non-zero fields of the route config and its
+// exclude option override the ones of the config
+func (c *Config) mergeRouteConfig(routeConfig *Config) *Config {
+	merged := c.clone()
+	merged.RouteConfig = nil
+	overrideFields(reflect.ValueOf(merged).Elem(), reflect.ValueOf(routeConfig).Elem())
+
+	excludeOpt := *c.ExcludeOpt
+	if routeConfig.ExcludeOpt != nil {
+		overrideFields(reflect.ValueOf(&excludeOpt).Elem(), reflect.ValueOf(routeConfig.ExcludeOpt).Elem())
+	}
+	merged.ExcludeOpt = &excludeOpt
+
+	return merged
+}
+
+// overrideFields is to set the exported fields of dst struct to the non-zero fields of src struct
+func overrideFields(dst, src reflect.Value) {
+	for idx := 0; idx < src.NumField(); idx++ {
+		if field := src.Field(idx); dst.Field(idx).CanSet() && !field.IsZero() {
+			dst.Field(idx).Set(field)
+		}
+	}
+}
+
+// clone is to copy the config, its exclude option and route configs, so preparing the copy leaves the given
+// config untouched
+func (c *Config) clone() *Config {
+	cloned := *c
+	if c.ExcludeOpt != nil {
+		excludeOpt := *c.ExcludeOpt
+		cloned.ExcludeOpt = &excludeOpt
+	}
+
+	if c.RouteConfig != nil {
+		cloned.RouteConfig = make(map[string]*Config, len(c.RouteConfig))
+		for prefix, routeConfig := range c.RouteConfig {
+			if routeConfig != nil {
+				routeConfig = routeConfig.clone()
+			}
+			cloned.RouteConfig[prefix] = routeConfig
+		}
+	}
+
+	return &cloned
 }
 
 func (c *Config) LogRequestHeader() bool {
@@ -74,6 +393,24 @@ func (c *Config) LogSuccessResponseBody() bool {
 	return c.ExcludeOpt.SuccessResponseBody == IncludeLog
 }
 
+func (c *Config) LogResponseBodyStatus(status int) bool {
+	for _, excludedStatus := range c.ExcludeResponseBodyStatuses {
+		if status == excludedStatus {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Config) LogSuccessRequestBody() bool {
+	if c.ExcludeOpt == nil {
+		return IncludeLog
+	}
+
+	return c.ExcludeOpt.SuccessRequestBody == IncludeLog
+}
+
 func (c *Config) LogFailedRequestOnly() bool {
 	if c.ExcludeOpt == nil {
 		return IncludeLog
@@ -82,6 +419,34 @@ func (c *Config) LogFailedRequestOnly() bool {
 	return c.ExcludeOpt.SuccessRequest == ExcludeLog
 }
 
+func (c *Config) LogClientIP() bool {
+	if c.ExcludeOpt == nil {
+		return IncludeLog
+	}
+
+	return c.ExcludeOpt.ClientIP == IncludeLog
+}
+
+func (c *Config) GetExcludedRequestHeaderKeys() []string {
+	if c.ExcludeOpt == nil {
+		return nil
+	}
+
+	return c.ExcludeOpt.RequestHeaderKeys
+}
+
+func (c *Config) GetFieldName(field string) string {
+	if c.FieldOpt == nil {
+		return field
+	}
+
+	if name, ok := c.FieldOpt.FieldNames[field]; ok && len(name) > 0 {
+		return name
+	}
+
+	return field
+}
+
 func (c *Config) GetEventPrefix() string {
 	if c.FieldOpt == nil || len(c.FieldOpt.EventPrefix) == 0 {
 		return EventPrefix + URLSeparator
@@ -89,3 +454,187 @@ func (c *Config) GetEventPrefix() string {
 
 	return c.FieldOpt.EventPrefix + URLSeparator
 }
+
+func (c *Config) GetMaskValue() string {
+	if c.ExcludeOpt == nil || len(c.ExcludeOpt.MaskValue) == 0 {
+		return wipedMessage
+	}
+
+	return c.ExcludeOpt.MaskValue
+}
+
+func (c *Config) GetSensitiveHeaderKeys() []string {
+	if c.SensitiveHeaderKeys == nil {
+		return []string{headerNameAuthorization}
+	}
+
+	return c.SensitiveHeaderKeys
+}
+
+func (c *Config) IsSkippedPath(path string) bool {
+	return matchPath(path, c.SkipPaths)
+}
+
+func (c *Config) IsAlwaysLoggedStatus(status int) bool {
+	if c.AlwaysLogStatuses == nil {
+		return status >= http.StatusInternalServerError
+	}
+
+	for _, alwaysLoggedStatus := range c.AlwaysLogStatuses {
+		if status == alwaysLoggedStatus {
+			return true
+		}
+	}
+
+	return false
+}
+
+// logsBody is to check whether the request or response body of the path is logged, i.e: worth a body capture slot
+func (c *Config) logsBody(path string) bool {
+	conf := c.GetRouteConfig(path)
+	return !conf.DisableIngressLog && !conf.IsNoBodyLogPath(path) && (conf.LogRequestBody() || conf.LogResponseBody())
+}
+
+// acquireBodyCapture is to take a body capture slot without waiting, false when every slot is taken
+func (c *Config) acquireBodyCapture() bool {
+	if c.bodyCaptureSem == nil {
+		return true
+	}
+
+	select {
+	case c.bodyCaptureSem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseBodyCapture is to return the body capture slot taken by acquireBodyCapture
+func (c *Config) releaseBodyCapture() {
+	if c.bodyCaptureSem != nil {
+		<-c.bodyCaptureSem
+	}
+}
+
+func (c *Config) IsSkippedMethod(method string) bool {
+	for _, skippedMethod := range c.SkipMethods {
+		if strings.EqualFold(method, skippedMethod) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) IsNoBodyLogPath(path string) bool {
+	for _, pathRegexp := range c.noBodyLogPathRegexps {
+		if pathRegexp.MatchString(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) IsStreamingPath(path string) bool {
+	return matchPath(path, c.StreamingPaths)
+}
+
+// matchPath is to check whether the path matches any of the patterns exactly,
+// or by prefix when the pattern ends with "*"
+func matchPath(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, wildcardSuffix) {
+			if strings.HasPrefix(path, strings.TrimSuffix(pattern, wildcardSuffix)) {
+				return true
+			}
+			continue
+		}
+
+		if path == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) IsLoggableContentType(contentType string) bool {
+	if len(c.LoggableContentTypes) == 0 {
+		return true
+	}
+
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, loggable := range c.LoggableContentTypes {
+		if strings.HasPrefix(contentType, strings.ToLower(loggable)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) GetRequestIDHeaders() []string {
+	if len(c.RequestIDHeaders) == 0 {
+		return []string{headerNameRequestID}
+	}
+
+	return c.RequestIDHeaders
+}
+
+// GetRouteConfig is to get the effective config of the request path, the matching RouteConfig merged over the config
+func (c *Config) GetRouteConfig(path string) *Config {
+	var (
+		routeConfig  = c
+		longestMatch = -1
+	)
+
+	for prefix, conf := range c.routeConfigs {
+		prefix = strings.TrimSuffix(prefix, wildcardSuffix)
+		if conf == nil || len(prefix) <= longestMatch || !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		routeConfig = conf
+		longestMatch = len(prefix)
+	}
+
+	return routeConfig
+}
+
+// generateID is to generate the context id of the request without an incoming request id
+func (c *Config) generateID() string {
+	if c.IDGenerator != nil {
+		return c.IDGenerator()
+	}
+
+	return uuid.New().String()
+}
+
+// getMaxBodyCaptureBytes is to get the maximum request body bytes read for the log, MaxBodyBytes when it is set,
+// otherwise the default 1MB
+func (c *Config) getMaxBodyCaptureBytes() int {
+	if c.MaxBodyBytes > 0 {
+		return c.MaxBodyBytes
+	}
+
+	return defaultMaxBodyCaptureBytes
+}
+
+func (c *Config) IsSlowRequest(timeTakenInMS int64) bool {
+	return c.SlowRequestThresholdMs > 0 && timeTakenInMS > c.SlowRequestThresholdMs
+}
+
+// renameFields is to rename the data map keys based on FieldOption.FieldNames
+func (c *Config) renameFields(dataMap map[string]interface{}) map[string]interface{} {
+	if c.FieldOpt == nil || len(c.FieldOpt.FieldNames) == 0 {
+		return dataMap
+	}
+
+	renamed := make(map[string]interface{}, len(dataMap))
+	for key, value := range dataMap {
+		renamed[c.GetFieldName(key)] = value
+	}
+
+	return renamed
+}
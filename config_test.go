@@ -0,0 +1,101 @@
+package httpmiddleware
+
+import (
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestConfigIsSkippedPath(t *testing.T) {
+	config := NewConfig(&Config{SkipPaths: []string{"/healthz", "/metrics/*"}})
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/healthz", expected: true},
+		{path: "/healthz/live", expected: false},
+		{path: "/metrics/", expected: true},
+		{path: "/metrics/http", expected: true},
+		{path: "/metrics", expected: false},
+		{path: "/hello", expected: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, config.IsSkippedPath(tt.path), tt.path)
+	}
+}
+
+func TestConfigIsNoBodyLogPath(t *testing.T) {
+	config := NewConfig(&Config{NoBodyLogPathPatterns: []string{"^/internal/debug/", `^/users/\d+/secret$`}})
+
+	assert.True(t, config.IsNoBodyLogPath("/internal/debug/pprof"))
+	assert.True(t, config.IsNoBodyLogPath("/users/123/secret"))
+	assert.False(t, config.IsNoBodyLogPath("/users/abc/secret"))
+	assert.False(t, config.IsNoBodyLogPath("/hello"))
+
+	assert.Panics(t, func() {
+		NewConfig(&Config{NoBodyLogPathPatterns: []string{"("}})
+	})
+}
+
+func TestConfigIsLoggableContentType(t *testing.T) {
+	assert.True(t, NewConfig(&Config{}).IsLoggableContentType("image/png"))
+
+	config := NewConfig(&Config{LoggableContentTypes: []string{"application/json", "text/"}})
+	assert.True(t, config.IsLoggableContentType("application/json; charset=utf-8"))
+	assert.True(t, config.IsLoggableContentType("Text/Plain"))
+	assert.False(t, config.IsLoggableContentType("image/png"))
+	assert.False(t, config.IsLoggableContentType(""))
+}
+
+func TestConfigGetRouteConfig(t *testing.T) {
+	config := NewConfig(&Config{
+		MaxBodyBytes:        1024,
+		SensitiveHeaderKeys: []string{"X-Api-Key"},
+		ExcludeOpt:          &ExcludeOption{MaskBodyFields: []string{"password"}},
+		RouteConfig: map[string]*Config{
+			"/upload":   {ExcludeOpt: &ExcludeOption{RequestBody: true}},
+			"/api/*":    {MaxBodyBytes: 16},
+			"/api/v1/*": {MaxBodyBytes: 32},
+		},
+	})
+
+	uploadConfig := config.GetRouteConfig("/upload/avatar")
+	assert.True(t, uploadConfig.ExcludeOpt.RequestBody)
+	assert.Equal(t, 1024, uploadConfig.MaxBodyBytes)
+	assert.Equal(t, 16, config.GetRouteConfig("/api/v2/users").MaxBodyBytes)
+	assert.Equal(t, 32, config.GetRouteConfig("/api/v1/users").MaxBodyBytes)
+	assert.Equal(t, config, config.GetRouteConfig("/hello"))
+
+	// the route config only overrides its non-zero fields
+	for _, path := range []string{"/upload/avatar", "/api/v1/users"} {
+		routeConfig := config.GetRouteConfig(path)
+		assert.Equal(t, []string{"password"}, routeConfig.ExcludeOpt.MaskBodyFields)
+		assert.Equal(t, []string{"X-Api-Key"}, routeConfig.GetSensitiveHeaderKeys())
+	}
+}
+
+func TestConfigRenameFields(t *testing.T) {
+	dataMap := map[string]interface{}{
+		FieldStatus:     200,
+		FieldDurationMs: int64(10),
+		FieldURL:        "GET /hello",
+	}
+
+	assert.Equal(t, dataMap, NewConfig(&Config{}).renameFields(dataMap))
+
+	config := NewConfig(&Config{
+		FieldOpt: &FieldOption{
+			FieldNames: map[string]string{
+				FieldStatus:     "http.status_code",
+				FieldDurationMs: "duration",
+			},
+		},
+	})
+	assert.Equal(t, map[string]interface{}{
+		"http.status_code": 200,
+		"duration":         int64(10),
+		FieldURL:           "GET /hello",
+	}, config.renameFields(dataMap))
+}
@@ -1,15 +1,52 @@
 package httpmiddleware
 
 const (
-	FieldType           = "type"
-	FieldURL            = "url_path"
-	FieldReqHeader      = "req_header"
-	FieldReqBody        = "req_body"
-	FieldResponseHeader = "rsp_header"
-	FieldStatus         = "status"
-	FieldResponseBody   = "rsp_body"
-	FieldDurationMs     = "duration_ms"
-	FieldReqTimestamp   = "req_timestamp"
+	FieldType                  = "type"
+	FieldURL                   = "url_path" // combined "METHOD URL", deprecated in favor of FieldMethod and FieldPath
+	FieldMethod                = "method"
+	FieldPath                  = "path"
+	FieldReqHeader             = "req_header"
+	FieldReqBody               = "req_body"
+	FieldResponseHeader        = "rsp_header"
+	FieldStatus                = "status"
+	FieldResponseBody          = "rsp_body"
+	FieldDurationMs            = "duration_ms"
+	FieldDurationUs            = "duration_us"
+	FieldDurationNs            = "duration_ns"
+	FieldReqTimestamp          = "req_timestamp"
+	FieldClientIP              = "client_ip"
+	FieldReqSize               = "req_size"
+	FieldResponseSize          = "rsp_size"
+	FieldSlow                  = "slow"
+	FieldQueryParams           = "query_params"
+	FieldTraceID               = "trace_id"
+	FieldSpanID                = "span_id"
+	FieldRoute                 = "route"
+	FieldError                 = "error"
+	FieldUserAgent             = "user_agent"
+	FieldReferer               = "referer"
+	FieldTimedOut              = "timed_out"
+	FieldTLSVersion            = "tls_version"
+	FieldTLSCipher             = "tls_cipher"
+	FieldPanicStack            = "panic_stack"
+	FieldClientDisconnected    = "client_disconnected"
+	FieldProto                 = "proto"
+	FieldContentLengthMismatch = "content_length_mismatch"
+	FieldResponseTrailer       = "rsp_trailer"
+	FieldReqTrailer            = "req_trailer"
+	FieldEvent                 = "event"
+	FieldDoubleWriteHeader     = "double_write_header"
+	FieldReqBodyEncoding       = "req_body_encoding"
+	FieldRouteParams           = "route_params"
+	FieldServerID              = "server_id"
+	FieldReqBodyReadError      = "req_body_read_error"
+	FieldBodySizeRatio         = "body_size_ratio"
+	FieldHost                  = "host"
+	FieldScheme                = "scheme"
+	FieldTruncatedFields       = "truncated_fields"
+	FieldParentSpanID          = "parent_span_id"
+	FieldBodyReadMs            = "body_read_ms"
+	FieldRequestFingerprint    = "req_fingerprint"
 )
 
 const (
@@ -18,12 +55,41 @@ const (
 )
 
 const (
-	headerNameRequestID = "x-request-id"
+	headerNameRequestID       = "x-request-id"
+	headerNameAuthorization   = "Authorization"
+	headerNameForwardedFor    = "X-Forwarded-For"
+	headerNameRealIP          = "X-Real-IP"
+	headerNameContentType     = "Content-Type"
+	headerNameUserAgent       = "User-Agent"
+	headerNameReferer         = "Referer"
+	headerNameContentEncoding = "Content-Encoding"
+	headerNameTrailer         = "Trailer"
+	headerNameForwardedProto  = "X-Forwarded-Proto"
+	headerNameTraceParent     = "Traceparent"
 
 	EventPrefix  = "events"
 	URLSeparator = "/"
 )
 
 const (
-	wipedMessage = "-"
+	wildcardSuffix         = "*"
+	contentTypeEventStream = "text/event-stream"
+	contentTypeOctetStream = "application/octet-stream"
+	schemeHTTP             = "http"
+	schemeHTTPS            = "https"
+)
+
+const (
+	// defaultMaxBodyCaptureBytes bounds the request body read for the log, the remainder is streamed to the handler
+	defaultMaxBodyCaptureBytes = 1 << 20
+)
+
+const (
+	wipedMessage        = "-"
+	truncatedMessage    = "...(truncated)"
+	bodyTooLargeMessage = "body too large (%d bytes)"
+	omittedMessage      = "...[%d bytes omitted]..."
+	binaryBodyMessage   = "<binary %s body, %d bytes>"
+
+	headerTruncatedSuffix = "..."
 )
@@ -0,0 +1,48 @@
+package httpmiddleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/muhammad-fakhri/log"
+)
+
+// GetContextID is to get the context id set by the middleware in the request context,
+// empty string is returned when the context has none
+func GetContextID(ctx context.Context) string {
+	if data, ok := ctx.Value(log.ContextDataMapKey).(map[string]string); ok {
+		return data[log.ContextIdKey]
+	}
+
+	return ""
+}
+
+// SetContextIDHeader is to propagate the context id of ctx into the outgoing request x-request-id header,
+// so the downstream service logs the same id. The header is left untouched when ctx has no context id
+func SetContextIDHeader(ctx context.Context, req *http.Request) {
+	setContextIDHeader(ctx, req, headerNameRequestID)
+}
+
+func setContextIDHeader(ctx context.Context, req *http.Request, headerName string) {
+	if contextID := GetContextID(ctx); len(contextID) > 0 {
+		req.Header.Set(headerName, contextID)
+	}
+}
+
+// getContextError is to get the handler error stored in the context as error or *error
+func getContextError(ctx context.Context, key interface{}) error {
+	if key == nil {
+		return nil
+	}
+
+	switch err := ctx.Value(key).(type) {
+	case error:
+		return err
+	case *error:
+		if err != nil {
+			return *err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package httpmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestGetContextID(t *testing.T) {
+	assert.Empty(t, GetContextID(context.Background()))
+
+	ctx := context.WithValue(context.Background(), log.ContextDataMapKey, map[string]string{log.ContextIdKey: defContextid})
+	assert.Equal(t, defContextid, GetContextID(ctx))
+}
+
+func TestSetContextIDHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/downstream", nil)
+	SetContextIDHeader(context.Background(), req)
+	assert.Empty(t, req.Header.Get("X-Request-ID"))
+
+	ctx := context.WithValue(context.Background(), log.ContextDataMapKey, map[string]string{log.ContextIdKey: defContextid})
+	SetContextIDHeader(ctx, req)
+	assert.Equal(t, defContextid, req.Header.Get("X-Request-ID"))
+}
+
+func TestEgressLogPropagateContextID(t *testing.T) {
+	var receivedRequestID string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedRequestID = r.Header.Get("X-Request-ID")
+	}))
+	defer mockServer.Close()
+
+	client := &http.Client{Transport: NewEgressLogMiddleware(log.NewLogger("log-egress-middleware"), nil)}
+
+	ctx := context.WithValue(context.Background(), log.ContextDataMapKey, map[string]string{log.ContextIdKey: defContextid})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, mockServer.URL, nil)
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, defContextid, receivedRequestID)
+	// the caller's request is not modified
+	assert.Empty(t, req.Header.Get("X-Request-ID"))
+}
@@ -0,0 +1,101 @@
+package httpmiddleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+)
+
+// bodyDigest is the SHA-256 digest of the body computed as the body streams through, so the body longer than
+// the capture limit is fingerprinted without being buffered
+type bodyDigest struct {
+	mu       sync.Mutex // the request body may still be read by the timed out handler
+	hash     hash.Hash
+	size     int
+	complete bool // true: the whole body is hashed
+}
+
+func newBodyDigest() *bodyDigest {
+	return &bodyDigest{hash: sha256.New()}
+}
+
+// hashBody is to get the digest of the completely captured body
+func hashBody(body string) *bodyDigest {
+	digest := newBodyDigest()
+	digest.Write([]byte(body))
+	digest.finish()
+	return digest
+}
+
+func (d *bodyDigest) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hash.Write(p)
+	d.size += len(p)
+	return len(p), nil
+}
+
+// finish is to mark the whole body as hashed
+func (d *bodyDigest) finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.complete = true
+}
+
+// String is to get the logged digest and length of the body, e.g: "sha256:<hex>;len=12345". It is "-" when
+// the body is not completely hashed, e.g: the handler doesn't read the request body to the end
+func (d *bodyDigest) String() string {
+	if d == nil {
+		return wipedMessage
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.complete {
+		return wipedMessage
+	}
+
+	return fmt.Sprintf("sha256:%s;len=%d", hex.EncodeToString(d.hash.Sum(nil)), d.size)
+}
+
+// digestBody is to hash the body as it is read, e.g: by the handler
+func digestBody(body *io.ReadCloser) *bodyDigest {
+	digest := newBodyDigest()
+	*body = &digestReader{ReadCloser: *body, digest: digest}
+	return digest
+}
+
+// digestReader is a body hashing the bytes read from it
+type digestReader struct {
+	io.ReadCloser
+	digest *bodyDigest
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.digest.Write(p[:n])
+	if err == io.EOF {
+		r.digest.finish()
+	}
+	return n, err
+}
+
+// isOversizedBody is to check whether the body of the size is logged as its digest
+func (c *Config) isOversizedBody(size int) bool {
+	return c.HashOversizedBody && c.MaxBodyBytes > 0 && size > c.MaxBodyBytes
+}
+
+// formatBodyDigest is to log the oversized body as its digest, "-" when the content type is not loggable
+func (c *Config) formatBodyDigest(contentType string, digest *bodyDigest) string {
+	if !c.IsLoggableContentType(contentType) {
+		return wipedMessage
+	}
+
+	return digest.String()
+}
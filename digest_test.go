@@ -0,0 +1,36 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+const helloWorldDigest = "sha256:dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f;len=13"
+
+func TestConfigFormatResponseBodyHashOversized(t *testing.T) {
+	config := NewConfig(&Config{MaxBodyBytes: 10, HashOversizedBody: true})
+
+	assert.Equal(t, "short", config.formatResponseBody(&LogResponse{Body: "short"}))
+	assert.Equal(t, helloWorldDigest, config.formatResponseBody(&LogResponse{Body: "Hello, World!"}))
+}
+
+func TestLogIngressHashOversizedBody(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{MaxBodyBytes: 10, HashOversizedBody: true})
+
+	// the whole body is hashed as it streams through, not only the captured part
+	middleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("Hello, World!")))
+	assert.Equal(t, helloWorldDigest, hook.LastEntry().Data[FieldReqBody])
+	assert.Equal(t, helloWorldDigest, hook.LastEntry().Data[FieldResponseBody])
+
+	// the request body not read to the end can not be hashed
+	middleware.Enforce(http.HandlerFunc(statusHandler)).ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodPost, "/status?code=200", strings.NewReader("Hello, World!")))
+	assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldReqBody])
+}
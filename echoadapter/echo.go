@@ -0,0 +1,36 @@
+// Package echoadapter provides the ingress log middleware for github.com/labstack/echo
+package echoadapter
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/muhammad-fakhri/httpmiddleware"
+)
+
+// Enforce is to apply log ingress middleware to the 'next' echo handler. Error returned by the handler
+// is rendered by the echo error handler within the middleware, so the error response is logged as well,
+// and the error is not returned to avoid rendering it again
+func Enforce(ingressLog *httpmiddleware.IngressLog) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var (
+				response       = c.Response()
+				originalWriter = response.Writer
+			)
+
+			handler := ingressLog.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				response.Writer = w
+
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+			}))
+			handler.ServeHTTP(originalWriter, c.Request())
+
+			response.Writer = originalWriter
+			return nil
+		}
+	}
+}
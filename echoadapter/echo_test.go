@@ -0,0 +1,64 @@
+package echoadapter
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/labstack/echo/v4"
+	"github.com/muhammad-fakhri/httpmiddleware"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestEnforce(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+
+	e := echo.New()
+	var handledErrors int
+	defaultErrorHandler := e.HTTPErrorHandler
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		handledErrors++
+		defaultErrorHandler(err, c)
+	}
+	e.Use(Enforce(httpmiddleware.NewIngressLogMiddleware(logger)))
+	e.POST("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusCreated, c.Param("id"))
+	})
+	e.GET("/error", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	})
+	e.GET("/panic", func(c echo.Context) error {
+		panic("unexpected")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/123", bytes.NewReader([]byte(`{"name":"shopee"}`)))
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "123", recorder.Body.String())
+
+	entry := hook.LastEntry()
+	assert.Equal(t, http.MethodPost+" /users/123", entry.Data[httpmiddleware.FieldURL])
+	assert.Equal(t, http.StatusCreated, entry.Data[httpmiddleware.FieldStatus])
+	assert.Equal(t, `{"name":"shopee"}`, entry.Data[httpmiddleware.FieldReqBody])
+	assert.Equal(t, "123", entry.Data[httpmiddleware.FieldResponseBody])
+
+	req = httptest.NewRequest(http.MethodGet, "/error", nil)
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, http.StatusBadRequest, hook.LastEntry().Data[httpmiddleware.FieldStatus])
+	assert.Contains(t, hook.LastEntry().Data[httpmiddleware.FieldResponseBody], "invalid request")
+	assert.Equal(t, 1, handledErrors)
+
+	req = httptest.NewRequest(http.MethodGet, "/panic", nil)
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, http.StatusInternalServerError, hook.LastEntry().Data[httpmiddleware.FieldStatus])
+}
@@ -0,0 +1,30 @@
+module github.com/muhammad-fakhri/httpmiddleware/echoadapter
+
+go 1.17
+
+require (
+	github.com/c2fo/testify v0.0.0-20150827203832-fba96363964a
+	github.com/labstack/echo/v4 v4.9.1
+	github.com/muhammad-fakhri/httpmiddleware v0.0.0-00010101000000-000000000000
+	github.com/muhammad-fakhri/log v1.0.2
+)
+
+require (
+	github.com/google/uuid v1.1.1 // indirect
+	github.com/julienschmidt/httprouter v1.3.0 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/labstack/gommon v0.4.0 // indirect
+	github.com/mattn/go-colorable v0.1.11 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/sirupsen/logrus v1.4.2 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasttemplate v1.2.1 // indirect
+	go.opentelemetry.io/otel v1.10.0 // indirect
+	go.opentelemetry.io/otel/trace v1.10.0 // indirect
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5 // indirect
+	golang.org/x/net v0.0.0-20211015210444-4f30a5c0130f // indirect
+	golang.org/x/sys v0.0.0-20220227234510-4e6760a101f9 // indirect
+	golang.org/x/text v0.3.7 // indirect
+)
+
+replace github.com/muhammad-fakhri/httpmiddleware => ../
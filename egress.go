@@ -0,0 +1,167 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/muhammad-fakhri/log"
+)
+
+const (
+	valueLogTypeEgress = "egress_http"
+)
+
+// EgressLog represents concrete type of the outbound http log, implementing http.RoundTripper
+type EgressLog struct {
+	logger log.Logger
+	config *Config
+	base   http.RoundTripper
+}
+
+// NewEgressLogMiddleware is to initialize egress log round tripper wrapping the base transport,
+// http.DefaultTransport is used when base is nil, e.g: http.Client{Transport: NewEgressLogMiddleware(logger, nil)}
+func NewEgressLogMiddleware(logger log.Logger, base http.RoundTripper, opts ...Option) *EgressLog {
+	conf := defaultConfig()
+	for _, opt := range opts {
+		if opt != nil {
+			conf = opt.apply(conf)
+		}
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &EgressLog{
+		logger: logger,
+		config: NewConfig(conf),
+		base:   base,
+	}
+}
+
+// RoundTrip is to execute the outbound request with the base transport and log it, the context id
+// of the request context is logged and propagated into the request id header
+func (e *EgressLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	conf := e.config.GetRouteConfig(req.URL.Path)
+
+	requestIDHeader := conf.GetRequestIDHeaders()[0]
+	if len(GetContextID(req.Context())) > 0 && len(req.Header.Get(requestIDHeader)) == 0 {
+		// the round tripper must not modify the caller's request, so the header is set on a clone
+		req = req.Clone(req.Context())
+		setContextIDHeader(req.Context(), req, requestIDHeader)
+	}
+
+	if conf.DisableEgressLog {
+		return e.base.RoundTrip(req)
+	}
+
+	request := &LogRequest{
+		URL:    req.URL.String(),
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Method: req.Method,
+		Header: req.Header,
+	}
+
+	if conf.LogRequestBody() && req.Body != nil && req.Body != http.NoBody {
+		// the round tripper must not modify the caller's request, so the body is restored on a clone
+		req = req.Clone(req.Context())
+		body, _, bodySize, err := conf.getRequestBody(req, conf.getMaxBodyCaptureBytes())
+		if err != nil {
+			req.Body.Close()
+			return nil, err
+		}
+
+		request.Body = body
+		request.BodySize = bodySize
+		if conf.isOversizedBody(bodySize) {
+			// the transport reads the rest of the body, it is hashed as it is sent
+			request.bodyDigest = digestBody(&req.Body)
+		}
+	}
+
+	requestTimestamp := time.Now()
+	resp, err := e.base.RoundTrip(req)
+	timeTaken := time.Since(requestTimestamp).Milliseconds()
+
+	dataMap := make(map[string]interface{})
+	dataMap[FieldType] = valueLogTypeEgress
+	conf.appendURLFields(dataMap, request)
+	dataMap[FieldReqTimestamp] = requestTimestamp.Unix()
+	dataMap[FieldDurationMs] = timeTaken
+	dataMap[FieldReqSize] = request.BodySize
+
+	if conf.LogRequestHeader() {
+		dataMap[FieldReqHeader] = headerValue(conf.formatRequestHeader(request.Header), conf.FlattenHeaders)
+	}
+
+	if conf.LogRequestBody() && conf.isOversizedBody(request.BodySize) {
+		dataMap[FieldReqBody] = conf.formatBodyDigest(request.Header.Get(headerNameContentType), request.bodyDigest)
+	} else if conf.LogRequestBody() {
+		dataMap[FieldReqBody] = conf.formatBody(request.Header.Get(headerNameContentType), request.Body)
+	}
+
+	status := 0
+	if err != nil {
+		dataMap[FieldError] = err.Error()
+	} else {
+		status = resp.StatusCode
+		e.appendResponse(conf, dataMap, req.URL.Path, resp)
+	}
+	dataMap[FieldStatus] = status
+
+	level := conf.getLogLevel(status)
+	if err != nil && conf.StatusBasedLogLevel {
+		level = conf.getLogLevel(http.StatusInternalServerError)
+	}
+
+	logMap(req.Context(), e.logger, level, conf.renameFields(dataMap))
+
+	return resp, err
+}
+
+// appendResponse is to add the response fields into the data map, up to the capture limit of the response body
+// is read and restored for the caller, the remainder is left unread. The streamed response body is not read
+func (e *EgressLog) appendResponse(conf *Config, dataMap map[string]interface{}, path string, resp *http.Response) {
+	if conf.LogResponseHeader() {
+		dataMap[FieldResponseHeader] = headerValue(conf.formatResponseHeader(resp.Header), conf.FlattenHeaders)
+	}
+
+	if !conf.LogResponseBody() || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+
+	if conf.IsStreamingPath(path) || strings.HasPrefix(resp.Header.Get(headerNameContentType), contentTypeEventStream) {
+		dataMap[FieldResponseBody] = wipedMessage
+		return
+	}
+
+	// the caller still gets the read error after the read part of the body
+	bodyBytes, complete, err := getBodyBytes(&resp.Body, conf.getMaxBodyCaptureBytes())
+	if err != nil {
+		dataMap[FieldError] = err.Error()
+	}
+
+	response := &LogResponse{
+		Status:        resp.StatusCode,
+		Header:        resp.Header,
+		Body:          string(bodyBytes),
+		BodySize:      len(bodyBytes),
+		BodyTruncated: !complete,
+	}
+	if !complete {
+		response.Body += truncatedMessage
+		if int(resp.ContentLength) > response.BodySize {
+			response.BodySize = int(resp.ContentLength)
+		}
+	}
+
+	dataMap[FieldResponseSize] = response.BodySize
+	if !conf.isResponseBodyStatusLogged(resp.StatusCode) {
+		dataMap[FieldResponseBody] = wipedMessage
+		return
+	}
+
+	dataMap[FieldResponseBody] = conf.formatResponseBody(response)
+}
@@ -0,0 +1,123 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestEgressLogRoundTrip(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-egress-middleware")
+
+	mockServer := httptest.NewServer(http.HandlerFunc(echoHandler))
+	defer mockServer.Close()
+
+	client := &http.Client{Transport: NewEgressLogMiddleware(logger, nil)}
+
+	ctx := context.WithValue(context.Background(), log.ContextDataMapKey, map[string]string{log.ContextIdKey: defContextid})
+	reqBody := `{"name":"shopee"}`
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, mockServer.URL+"/echo?page=1", bytes.NewReader([]byte(reqBody)))
+	req.Header.Add("Authorization", "Bearer abcdefghijkl")
+
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+
+	// the response body is restored for the caller
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, reqBody, string(respBody))
+
+	entry := hook.LastEntry()
+	assert.Equal(t, valueLogTypeEgress, entry.Data[FieldType])
+	assert.Equal(t, defContextid, entry.Data["context_id"])
+	assert.Equal(t, http.MethodPost, entry.Data[FieldMethod])
+	assert.Equal(t, "/echo", entry.Data[FieldPath])
+	assert.Equal(t, http.StatusOK, entry.Data[FieldStatus])
+	assert.Equal(t, reqBody, entry.Data[FieldReqBody])
+	assert.Equal(t, reqBody, entry.Data[FieldResponseBody])
+	assert.Empty(t, entry.Data[FieldReqHeader].(http.Header).Get("Authorization"))
+	assert.Equal(t, "application/json", entry.Data[FieldResponseHeader].(http.Header).Get("Content-Type"))
+}
+
+func TestEgressLogRoundTripError(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-egress-middleware")
+
+	mockServer := httptest.NewServer(http.HandlerFunc(echoHandler))
+	mockServer.Close()
+
+	client := &http.Client{Transport: NewEgressLogMiddleware(logger, nil)}
+	_, err := client.Get(mockServer.URL + "/echo")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, 0, hook.LastEntry().Data[FieldStatus])
+	assert.NotEmpty(t, hook.LastEntry().Data[FieldError])
+}
+
+func TestEgressLogRoundTripCaptureLimit(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-egress-middleware")
+
+	body := strings.Repeat("a", 64)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer mockServer.Close()
+
+	client := &http.Client{Transport: NewEgressLogMiddleware(logger, nil, &Config{MaxBodyBytes: 16})}
+	resp, err := client.Post(mockServer.URL+"/download", "text/plain", strings.NewReader(body))
+	assert.Nil(t, err)
+
+	// the caller still reads the whole response body
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, body, string(respBody))
+
+	entry := hook.LastEntry()
+	assert.Equal(t, body[:16]+truncatedMessage, entry.Data[FieldReqBody])
+	assert.Equal(t, body[:16]+truncatedMessage, entry.Data[FieldResponseBody])
+	assert.Equal(t, 64, entry.Data[FieldResponseSize])
+}
+
+func TestEgressLogRoundTripEventStream(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-egress-middleware")
+
+	done := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentTypeEventStream)
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer mockServer.Close()
+	defer close(done)
+
+	client := &http.Client{Transport: NewEgressLogMiddleware(logger, nil)}
+	resp, err := client.Get(mockServer.URL + "/events")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	// the round trip returns before the stream ends
+	assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldResponseBody])
+
+	buf := make([]byte, 64)
+	n, _ := resp.Body.Read(buf)
+	assert.Equal(t, "data: hello\n\n", string(buf[:n]))
+}
+
+func TestEgressLogDisableEgressLog(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-egress-middleware")
+
+	mockServer := httptest.NewServer(http.HandlerFunc(echoHandler))
+	defer mockServer.Close()
+
+	client := &http.Client{Transport: NewEgressLogMiddleware(logger, nil, &Config{DisableEgressLog: true})}
+	resp, err := client.Get(mockServer.URL + "/echo")
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, hook.LastEntry())
+}
@@ -0,0 +1,117 @@
+// Package fiberadapter provides the ingress log middleware for github.com/gofiber/fiber
+package fiberadapter
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/muhammad-fakhri/httpmiddleware"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// Enforce is to apply log ingress middleware to the fiber app, e.g: app.Use(fiberadapter.Enforce(ingressLog)).
+// The fasthttp request is logged as its net/http equivalent, and the fiber response is logged once the
+// handler returns. The context id is set in the user context, see fiber.Ctx UserContext. Error returned by
+// the handler is rendered by the app error handler within the middleware, so the error response is logged
+// as well. HandlerTimeout is not applied, as the fiber context is reused once the middleware returns, and
+// the streamed response body, see fiber.Ctx SendStream, is not logged
+func Enforce(ingressLog *httpmiddleware.IngressLog) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var request http.Request
+		if err := fasthttpadaptor.ConvertRequest(c.Context(), &request, true); err != nil {
+			return err
+		}
+
+		writer := &responseWriter{ctx: c, header: make(http.Header)}
+		handler := ingressLog.EnforceWithRouteFunc(routePath(c), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the headers set by the middleware, e.g: the echoed request id
+			for key, values := range w.Header() {
+				for _, value := range values {
+					c.Response().Header.Add(key, value)
+				}
+			}
+			c.SetUserContext(r.Context())
+
+			if err := c.Next(); err != nil {
+				if err = c.App().ErrorHandler(c, err); err != nil {
+					_ = c.SendStatus(fiber.StatusInternalServerError)
+				}
+			}
+
+			writer.capture(w)
+		}))
+		handler.ServeHTTP(writer, httpmiddleware.WithoutHandlerTimeout(request.WithContext(c.UserContext())))
+
+		return nil
+	}
+}
+
+// routePath is to get the route path matched by fiber, it is complete only after the handler returns
+func routePath(c *fiber.Ctx) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if route := c.Route(); route != nil {
+			return route.Path
+		}
+
+		return ""
+	}
+}
+
+// responseWriter bridges the fiber response to the middleware response wrapper. The fiber response is
+// captured as it is, while the response written by the middleware itself, e.g: the recovered panic
+// response, is written to fiber
+type responseWriter struct {
+	ctx       *fiber.Ctx
+	header    http.Header
+	capturing bool // true: the written response is the fiber response being captured
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.capturing {
+		return
+	}
+
+	response := w.ctx.Response()
+	response.ResetBody()
+	for key, values := range w.header {
+		response.Header.Del(key)
+		for _, value := range values {
+			response.Header.Add(key, value)
+		}
+	}
+	response.SetStatusCode(code)
+}
+
+func (w *responseWriter) Write(body []byte) (int, error) {
+	if !w.capturing {
+		w.ctx.Response().AppendBody(body)
+	}
+
+	return len(body), nil
+}
+
+// capture is to write the fiber response to the middleware response wrapper to be logged
+func (w *responseWriter) capture(wrapper http.ResponseWriter) {
+	w.capturing = true
+	defer func() { w.capturing = false }()
+
+	header := wrapper.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	w.ctx.Response().Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	wrapper.WriteHeader(w.ctx.Response().StatusCode())
+	if w.ctx.Response().IsBodyStream() {
+		// reading the stream would buffer the whole body, it is left for fasthttp to send
+		return
+	}
+	// the body is already buffered by fiber, the wrapper only copies it when the response body is logged
+	wrapper.Write(w.ctx.Response().Body())
+}
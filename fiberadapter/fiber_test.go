@@ -0,0 +1,100 @@
+package fiberadapter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/gofiber/fiber/v2"
+	"github.com/muhammad-fakhri/httpmiddleware"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestEnforce(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+
+	app := fiber.New()
+	app.Use(Enforce(httpmiddleware.NewIngressLogMiddleware(logger)))
+	app.Post("/users/:id", func(c *fiber.Ctx) error {
+		return c.Status(http.StatusCreated).SendString(c.Params("id"))
+	})
+	app.Get("/error", func(c *fiber.Ctx) error {
+		return fiber.NewError(http.StatusBadRequest, "invalid request")
+	})
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		panic("unexpected")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/123", bytes.NewReader([]byte(`{"name":"shopee"}`)))
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "123", string(body))
+
+	entry := hook.LastEntry()
+	assert.Equal(t, http.MethodPost+" /users/123", entry.Data[httpmiddleware.FieldURL])
+	assert.Equal(t, "/users/:id", entry.Data[httpmiddleware.FieldRoute])
+	assert.Equal(t, http.StatusCreated, entry.Data[httpmiddleware.FieldStatus])
+	assert.Equal(t, `{"name":"shopee"}`, entry.Data[httpmiddleware.FieldReqBody])
+	assert.Equal(t, "123", entry.Data[httpmiddleware.FieldResponseBody])
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/error", nil))
+	assert.Nil(t, err)
+	body, _ = ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid request", string(body))
+	assert.Equal(t, http.StatusBadRequest, hook.LastEntry().Data[httpmiddleware.FieldStatus])
+	assert.Equal(t, "invalid request", hook.LastEntry().Data[httpmiddleware.FieldResponseBody])
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, http.StatusInternalServerError, hook.LastEntry().Data[httpmiddleware.FieldStatus])
+}
+
+func TestEnforceHandlerTimeout(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+
+	app := fiber.New()
+	app.Use(Enforce(httpmiddleware.NewIngressLogMiddleware(logger, &httpmiddleware.Config{HandlerTimeout: 10 * time.Millisecond})))
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		time.Sleep(50 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	// the handler runs until it returns, the fiber context is not used after the middleware returns
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "done", string(body))
+	assert.Equal(t, http.StatusOK, hook.LastEntry().Data[httpmiddleware.FieldStatus])
+}
+
+func TestEnforceBodyStream(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+
+	app := fiber.New()
+	app.Use(Enforce(httpmiddleware.NewIngressLogMiddleware(logger)))
+	app.Get("/download", func(c *fiber.Ctx) error {
+		return c.SendStream(strings.NewReader("streamed content"), len("streamed content"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/download", nil))
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, "streamed content", string(body))
+	assert.Equal(t, http.StatusOK, hook.LastEntry().Data[httpmiddleware.FieldStatus])
+	assert.Equal(t, "", hook.LastEntry().Data[httpmiddleware.FieldResponseBody])
+}
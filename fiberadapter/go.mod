@@ -0,0 +1,27 @@
+module github.com/muhammad-fakhri/httpmiddleware/fiberadapter
+
+go 1.17
+
+require (
+	github.com/c2fo/testify v0.0.0-20150827203832-fba96363964a
+	github.com/gofiber/fiber/v2 v2.36.0
+	github.com/muhammad-fakhri/httpmiddleware v0.0.0-00010101000000-000000000000
+	github.com/muhammad-fakhri/log v1.0.2
+	github.com/valyala/fasthttp v1.38.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.4 // indirect
+	github.com/google/uuid v1.1.1 // indirect
+	github.com/julienschmidt/httprouter v1.3.0 // indirect
+	github.com/klauspost/compress v1.15.0 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/sirupsen/logrus v1.4.2 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/tcplisten v1.0.0 // indirect
+	go.opentelemetry.io/otel v1.10.0 // indirect
+	go.opentelemetry.io/otel/trace v1.10.0 // indirect
+	golang.org/x/sys v0.0.0-20220227234510-4e6760a101f9 // indirect
+)
+
+replace github.com/muhammad-fakhri/httpmiddleware => ../
@@ -0,0 +1,36 @@
+package httpmiddleware
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// requestFingerprint is to compute the SHA-1 hex digest of the request components, the headers are sorted by
+// the canonical name so the fingerprint doesn't depend on the configured order. The body is hashed as it is
+// received, ok is false when the body is included but not completely captured
+func (c *Config) requestFingerprint(request *LogRequest) (string, bool) {
+	if !c.FingerprintExcludeBody && request.rawBody == nil {
+		return "", false
+	}
+
+	headers := make([]string, 0, len(c.FingerprintHeaders))
+	for _, name := range c.FingerprintHeaders {
+		name = http.CanonicalHeaderKey(name)
+		headers = append(headers, name+":"+strings.Join(request.Header.Values(name), ","))
+	}
+	sort.Strings(headers)
+
+	hash := sha1.New()
+	hash.Write([]byte(request.Method + "\n" + request.Path + "\n"))
+	for _, header := range headers {
+		hash.Write([]byte(header + "\n"))
+	}
+	if !c.FingerprintExcludeBody {
+		hash.Write(request.rawBody)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
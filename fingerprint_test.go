@@ -0,0 +1,74 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestConfigRequestFingerprint(t *testing.T) {
+	config := NewConfig(&Config{
+		LogRequestFingerprint: true,
+		FingerprintHeaders:    []string{"x-tenant-id", "Authorization"},
+	})
+	newRequest := func(body string, header http.Header) *LogRequest {
+		return &LogRequest{Method: http.MethodPost, Path: "/orders", Header: header, Body: body, rawBody: []byte(body)}
+	}
+	header := http.Header{"Authorization": []string{"Bearer abc"}, "X-Tenant-Id": []string{"shopee"}}
+
+	fingerprint, ok := config.requestFingerprint(newRequest(`{"item":1}`, header))
+	assert.True(t, ok)
+	assert.Len(t, fingerprint, 40)
+
+	// the unlisted headers don't contribute to the fingerprint
+	otherHeader := http.Header{"Authorization": []string{"Bearer abc"}, "X-Tenant-Id": []string{"shopee"}, "User-Agent": []string{"curl"}}
+	otherFingerprint, _ := config.requestFingerprint(newRequest(`{"item":1}`, otherHeader))
+	assert.Equal(t, fingerprint, otherFingerprint)
+
+	otherFingerprint, _ = config.requestFingerprint(newRequest(`{"item":2}`, header))
+	assert.NotEqual(t, fingerprint, otherFingerprint)
+	otherFingerprint, _ = config.requestFingerprint(newRequest(`{"item":1}`, http.Header{"X-Tenant-Id": []string{"shopee"}}))
+	assert.NotEqual(t, fingerprint, otherFingerprint)
+
+	// the body which is not captured can not be fingerprinted
+	_, ok = config.requestFingerprint(&LogRequest{Method: http.MethodPost, Path: "/orders", Header: header, Body: wipedMessage})
+	assert.False(t, ok)
+
+	config.FingerprintExcludeBody = true
+	fingerprint, _ = config.requestFingerprint(newRequest(`{"item":1}`, header))
+	otherFingerprint, ok = config.requestFingerprint(&LogRequest{Method: http.MethodPost, Path: "/orders", Header: header})
+	assert.True(t, ok)
+	assert.Equal(t, fingerprint, otherFingerprint)
+}
+
+func TestLogIngressRequestFingerprint(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	body := strings.Repeat("a", 32)
+
+	middleware := NewIngressLogMiddleware(logger, &Config{LogRequestFingerprint: true})
+	handler := middleware.Enforce(http.HandlerFunc(echoHandler))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body)))
+	fingerprint := hook.LastEntry().Data[FieldRequestFingerprint]
+	assert.NotNil(t, fingerprint)
+
+	// the raw body is fingerprinted, the format of the logged body doesn't change it
+	assert.Nil(t, middleware.SetConfig(&Config{LogRequestFingerprint: true, HeadTailBytes: 4}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body)))
+	assert.Equal(t, fingerprint, hook.LastEntry().Data[FieldRequestFingerprint])
+
+	// the body longer than the capture limit is not fingerprinted
+	assert.Nil(t, middleware.SetConfig(&Config{LogRequestFingerprint: true, MaxBodyBytes: 16}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body)))
+	_, ok := hook.LastEntry().Data[FieldRequestFingerprint]
+	assert.False(t, ok)
+
+	// the body which is not captured is not fingerprinted
+	assert.Nil(t, middleware.SetConfig(&Config{LogRequestFingerprint: true, ExcludeOpt: &ExcludeOption{RequestBody: true}}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body)))
+	_, ok = hook.LastEntry().Data[FieldRequestFingerprint]
+	assert.False(t, ok)
+}
@@ -0,0 +1,30 @@
+package httpmiddleware
+
+import (
+	"encoding/json"
+	"mime"
+	"net/url"
+)
+
+const mediaTypeFormURLEncoded = "application/x-www-form-urlencoded"
+
+// formatFormBody is to convert application/x-www-form-urlencoded body into a JSON object of the form values,
+// e.g: {"a":["1"],"b":["2"]}, with the sensitive values redacted. It returns false when the body is not a valid form
+func (c *Config) formatFormBody(contentType string, body []byte) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != mediaTypeFormURLEncoded {
+		return "", false
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", false
+	}
+
+	formBody, err := json.Marshal(c.formatQueryParams(form))
+	if err != nil {
+		return "", false
+	}
+
+	return string(formBody), true
+}
@@ -0,0 +1,44 @@
+package httpmiddleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestConfigFormatFormBody(t *testing.T) {
+	config := NewConfig(&Config{SensitiveQueryKeys: []string{"password"}})
+
+	body, ok := config.formatFormBody("application/x-www-form-urlencoded; charset=utf-8", []byte("user=john&password=secret&tag=a&tag=b"))
+	assert.True(t, ok)
+	assert.Equal(t, `{"password":["-"],"tag":["a","b"],"user":["john"]}`, body)
+
+	_, ok = config.formatFormBody("application/json", []byte(`{"user":"john"}`))
+	assert.False(t, ok)
+
+	_, ok = config.formatFormBody("application/x-www-form-urlencoded", []byte("user=%zz"))
+	assert.False(t, ok)
+}
+
+func TestLogIngressParseFormBody(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{ParseFormBody: true, SensitiveQueryKeys: []string{"password"}})
+
+	var handlerBody string
+	handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		handlerBody = string(body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("user=john&password=secret"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "user=john&password=secret", handlerBody)
+	assert.Equal(t, `{"password":["-"],"user":["john"]}`, hook.LastEntry().Data[FieldReqBody])
+}
@@ -0,0 +1,56 @@
+// Package ginadapter provides the ingress log middleware for github.com/gin-gonic/gin
+package ginadapter
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhammad-fakhri/httpmiddleware"
+)
+
+// responseWriter routes the gin writes through the ingress log response wrapper
+type responseWriter struct {
+	gin.ResponseWriter
+	writer http.ResponseWriter
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.writer.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(body []byte) (int, error) {
+	return w.writer.Write(body)
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	return w.writer.Write([]byte(s))
+}
+
+// AddLogField forwards the field to the ingress log response wrapper, see httpmiddleware.AddLogField
+func (w *responseWriter) AddLogField(key string, val interface{}) {
+	httpmiddleware.AddLogField(w.writer, key, val)
+}
+
+// Enforce is to apply log ingress middleware to the gin handlers chain, the remaining handlers
+// are called with c.Next() in place of the 'next' handler. The matched route path e.g: "/users/:id" is logged as the route
+func Enforce(ingressLog *httpmiddleware.IngressLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		originalWriter := c.Writer
+
+		handler := ingressLog.EnforceWithRouteFunc(routePath(c), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.Request = r
+			c.Writer = &responseWriter{ResponseWriter: originalWriter, writer: w}
+			c.Next()
+		}))
+		handler.ServeHTTP(originalWriter, c.Request)
+
+		c.Writer = originalWriter
+	}
+}
+
+// routePath is to get the route path matched by gin, it is empty for the unmatched request
+func routePath(c *gin.Context) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return c.FullPath()
+	}
+}
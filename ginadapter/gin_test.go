@@ -0,0 +1,51 @@
+package ginadapter
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/gin-gonic/gin"
+	"github.com/muhammad-fakhri/httpmiddleware"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestEnforce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+
+	router := gin.New()
+	router.Use(Enforce(httpmiddleware.NewIngressLogMiddleware(logger)))
+	router.POST("/users/:id", func(c *gin.Context) {
+		httpmiddleware.AddLogField(c.Writer, "tenant", "shopee")
+		c.JSON(http.StatusCreated, gin.H{"id": c.Param("id")})
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("unexpected")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/123", bytes.NewReader([]byte(`{"name":"shopee"}`)))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, `{"id":"123"}`, recorder.Body.String())
+
+	entry := hook.LastEntry()
+	assert.Equal(t, http.MethodPost+" /users/123", entry.Data[httpmiddleware.FieldURL])
+	assert.Equal(t, http.StatusCreated, entry.Data[httpmiddleware.FieldStatus])
+	assert.Equal(t, "/users/:id", entry.Data[httpmiddleware.FieldRoute])
+	assert.Equal(t, "shopee", entry.Data["tenant"])
+	assert.Equal(t, `{"name":"shopee"}`, entry.Data[httpmiddleware.FieldReqBody])
+	assert.Equal(t, `{"id":"123"}`, entry.Data[httpmiddleware.FieldResponseBody])
+	assert.True(t, len(entry.Data["context_id"].(string)) > 0)
+
+	req = httptest.NewRequest(http.MethodGet, "/panic", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, http.StatusInternalServerError, hook.LastEntry().Data[httpmiddleware.FieldStatus])
+}
@@ -8,9 +8,12 @@ require (
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/muhammad-fakhri/log v1.0.2
 	github.com/sirupsen/logrus v1.4.2
+	go.opentelemetry.io/otel/trace v1.10.0
 )
 
 require (
 	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
-	golang.org/x/sys v0.0.0-20200323222414-85ca7c5b95cd // indirect
+	go.opentelemetry.io/otel v1.10.0 // indirect
+	golang.org/x/sys v0.0.0-20220227234510-4e6760a101f9 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
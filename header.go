@@ -0,0 +1,123 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// formatRequestHeader is to copy the request header to be logged, only the allowlisted keys when
+// RequestHeaderAllowlist is set, otherwise every key except the excluded ones. The sensitive keys are never logged
+func (c *Config) formatRequestHeader(header http.Header) http.Header {
+	if len(c.RequestHeaderAllowlist) > 0 {
+		loggedHeader := c.stripSensitiveHeader(allowlistHeader(header, c.RequestHeaderAllowlist))
+		return truncateHeaderValues(loggedHeader, c.MaxHeaderValueLength)
+	}
+
+	loggedHeader := c.stripSensitiveHeader(header.Clone())
+	for _, headerKey := range c.GetExcludedRequestHeaderKeys() {
+		loggedHeader.Del(headerKey)
+	}
+
+	return truncateHeaderValues(loggedHeader, c.MaxHeaderValueLength)
+}
+
+// formatResponseHeader is to copy the response header to be logged, only the allowlisted keys when
+// ResponseHeaderAllowlist is set, otherwise every key. The sensitive keys are never logged
+func (c *Config) formatResponseHeader(header http.Header) http.Header {
+	if len(c.ResponseHeaderAllowlist) > 0 {
+		loggedHeader := c.stripSensitiveHeader(allowlistHeader(header, c.ResponseHeaderAllowlist))
+		return truncateHeaderValues(loggedHeader, c.MaxHeaderValueLength)
+	}
+
+	return truncateHeaderValues(c.stripSensitiveHeader(header.Clone()), c.MaxHeaderValueLength)
+}
+
+// stripSensitiveHeader is to delete the sensitive keys from the logged header copy
+func (c *Config) stripSensitiveHeader(loggedHeader http.Header) http.Header {
+	for _, headerKey := range c.GetSensitiveHeaderKeys() {
+		loggedHeader.Del(headerKey)
+	}
+
+	return loggedHeader
+}
+
+func allowlistHeader(header http.Header, allowlist []string) http.Header {
+	loggedHeader := make(http.Header, len(allowlist))
+	for _, headerKey := range allowlist {
+		if values := header.Values(headerKey); len(values) > 0 {
+			loggedHeader[http.CanonicalHeaderKey(headerKey)] = append([]string{}, values...)
+		}
+	}
+
+	return loggedHeader
+}
+
+// truncateHeaderValues is to cut each value of the logged header copy to maxLength with "..." suffix, 0 means no limit
+func truncateHeaderValues(header http.Header, maxLength int) http.Header {
+	if maxLength <= 0 {
+		return header
+	}
+
+	for _, values := range header {
+		for idx, value := range values {
+			if len(value) > maxLength {
+				values[idx] = value[:maxLength] + headerTruncatedSuffix
+			}
+		}
+	}
+
+	return header
+}
+
+// headerValue is to get the logged value of the header, a map of single-valued keys to string and
+// multi-valued keys to []string when flatten is true, otherwise the header as it is
+func headerValue(header http.Header, flatten bool) interface{} {
+	if !flatten {
+		return header
+	}
+
+	flattened := make(map[string]interface{}, len(header))
+	for key, values := range header {
+		if len(values) == 1 {
+			flattened[key] = values[0]
+		} else {
+			flattened[key] = []string(values)
+		}
+	}
+
+	return flattened
+}
+
+// responseTrailer is to get the trailers of the response header, both the ones declared by the "Trailer" header
+// and the undeclared ones set with http.TrailerPrefix
+func responseTrailer(header http.Header) http.Header {
+	trailer := make(http.Header)
+	for _, declared := range header.Values(headerNameTrailer) {
+		for _, key := range strings.Split(declared, ",") {
+			key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+			if values, ok := header[key]; ok && len(key) > 0 {
+				trailer[key] = values
+			}
+		}
+	}
+
+	for key, values := range header {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			trailer[http.CanonicalHeaderKey(strings.TrimPrefix(key, http.TrailerPrefix))] = values
+		}
+	}
+
+	return trailer
+}
+
+// requestTrailer is to get the received request trailers, the declared trailers without value are omitted
+func requestTrailer(header http.Header) http.Header {
+	trailer := make(http.Header)
+	for key, values := range header {
+		if len(values) > 0 {
+			trailer[key] = values
+		}
+	}
+
+	return trailer
+}
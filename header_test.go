@@ -0,0 +1,123 @@
+package httpmiddleware
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestConfigFormatRequestHeader(t *testing.T) {
+	header := http.Header{}
+	header.Add("Authorization", "Bearer abcdefghijkl")
+	header.Add("X-Country", "ID")
+	header.Add("X-Exclude-Key", "excluded")
+	header.Add("Accept", "application/json")
+	header.Add("Accept", "text/plain")
+
+	config := NewConfig(&Config{ExcludeOpt: &ExcludeOption{RequestHeaderKeys: []string{"X-Exclude-Key"}}})
+	assert.Equal(t, http.Header{
+		"X-Country": []string{"ID"},
+		"Accept":    []string{"application/json", "text/plain"},
+	}, config.formatRequestHeader(header))
+
+	config.RequestHeaderAllowlist = []string{"accept", "x-exclude-key", "X-Missing", "Authorization"}
+	assert.Equal(t, http.Header{
+		"X-Exclude-Key": []string{"excluded"},
+		"Accept":        []string{"application/json", "text/plain"},
+	}, config.formatRequestHeader(header))
+
+	// the original header stays untouched
+	assert.Equal(t, "Bearer abcdefghijkl", header.Get("Authorization"))
+}
+
+func TestConfigFormatResponseHeader(t *testing.T) {
+	header := http.Header{}
+	header.Add("Content-Type", "application/json")
+	header.Add("Set-Cookie", "session=abcdefghijkl")
+
+	config := NewConfig(&Config{SensitiveHeaderKeys: []string{"Set-Cookie"}})
+	assert.Equal(t, http.Header{"Content-Type": []string{"application/json"}}, config.formatResponseHeader(header))
+
+	// the allowlisted sensitive key is still stripped
+	config.ResponseHeaderAllowlist = []string{"Content-Type", "Set-Cookie"}
+	assert.Equal(t, http.Header{"Content-Type": []string{"application/json"}}, config.formatResponseHeader(header))
+}
+
+func TestConfigFormatHeaderMaxHeaderValueLength(t *testing.T) {
+	header := http.Header{}
+	header.Add("Cookie", "session=abcdefghijkl")
+	header.Add("X-Very-Long-Header-Key", "ID")
+
+	config := NewConfig(&Config{MaxHeaderValueLength: 10})
+	assert.Equal(t, http.Header{
+		"Cookie":                 []string{"session=ab..."},
+		"X-Very-Long-Header-Key": []string{"ID"},
+	}, config.formatRequestHeader(header))
+
+	config.ResponseHeaderAllowlist = []string{"Cookie"}
+	assert.Equal(t, http.Header{"Cookie": []string{"session=ab..."}}, config.formatResponseHeader(header))
+
+	// the original header stays untouched
+	assert.Equal(t, "session=abcdefghijkl", header.Get("Cookie"))
+}
+
+func TestLogMessageFlattenHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Add("X-Country", "ID")
+	header.Add("Accept", "application/json")
+	header.Add("Accept", "text/plain")
+
+	msg := NewConfig(&Config{FlattenHeaders: true}).BuildLogMessage(context.Background(),
+		&LogRequest{Header: header}, &LogResponse{Header: http.Header{}}, 0, time.Now())
+	assert.Equal(t, map[string]interface{}{
+		"X-Country": "ID",
+		"Accept":    []string{"application/json", "text/plain"},
+	}, msg.dataMap()[FieldReqHeader])
+	assert.Equal(t, map[string]interface{}{}, msg.dataMap()[FieldResponseHeader])
+
+	msg = NewConfig(&Config{}).BuildLogMessage(context.Background(),
+		&LogRequest{Header: header}, &LogResponse{Header: http.Header{}}, 0, time.Now())
+	assert.Equal(t, header, msg.dataMap()[FieldReqHeader])
+}
+
+func TestResponseTrailer(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/grpc")
+	header.Set("Trailer", "Grpc-Status, grpc-message")
+	header.Set("Grpc-Status", "0")
+	header.Set("Grpc-Message", "OK")
+	header.Set(http.TrailerPrefix+"X-Checksum", "abc")
+
+	assert.Equal(t, http.Header{
+		"Grpc-Status":  []string{"0"},
+		"Grpc-Message": []string{"OK"},
+		"X-Checksum":   []string{"abc"},
+	}, responseTrailer(header))
+
+	assert.Equal(t, http.Header{}, responseTrailer(http.Header{"Content-Type": []string{"application/json"}}))
+}
+
+func TestLogIngressResponseTrailers(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{LogResponseTrailers: true})
+	handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte("data"))
+		w.Header().Set("Grpc-Status", "5")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader("data"))
+	req.Trailer = http.Header{"X-Checksum": []string{"abc"}, "X-Declared": nil}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, http.Header{"Grpc-Status": []string{"5"}}, hook.LastEntry().Data[FieldResponseTrailer])
+	assert.Equal(t, http.Header{"X-Checksum": []string{"abc"}}, hook.LastEntry().Data[FieldReqTrailer])
+}
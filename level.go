@@ -0,0 +1,78 @@
+package httpmiddleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/muhammad-fakhri/log"
+	"github.com/sirupsen/logrus"
+)
+
+// warnMapLogger is implemented by loggers supporting warn level data map
+type warnMapLogger interface {
+	WarnMap(ctx context.Context, dataMap map[string]interface{}, args ...interface{})
+}
+
+// errorMapLogger is implemented by loggers supporting error level data map
+type errorMapLogger interface {
+	ErrorMap(ctx context.Context, dataMap map[string]interface{}, args ...interface{})
+}
+
+// getLogLevel is to decide the log level of the ingress log based on the response status
+func (c *Config) getLogLevel(status int) logrus.Level {
+	if !c.StatusBasedLogLevel || c.isDowngradedStatus(status) {
+		return logrus.InfoLevel
+	}
+
+	switch {
+	case status >= http.StatusInternalServerError:
+		return logrus.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return logrus.WarnLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
+
+// isDowngradedStatus is to check whether the response status is logged as info regardless of StatusBasedLogLevel
+func (c *Config) isDowngradedStatus(status int) bool {
+	for _, downgradedStatus := range c.DowngradeStatuses {
+		if status == downgradedStatus {
+			return true
+		}
+	}
+
+	return false
+}
+
+// logMap is to emit the data map with the given level. Loggers which only support InfoMap
+// are written through their underlying entry, including the context data
+func logMap(ctx context.Context, logger log.Logger, level logrus.Level, dataMap map[string]interface{}) {
+	switch level {
+	case logrus.InfoLevel:
+		logger.InfoMap(ctx, dataMap)
+		return
+	case logrus.WarnLevel:
+		if l, ok := logger.(warnMapLogger); ok {
+			l.WarnMap(ctx, dataMap)
+			return
+		}
+	case logrus.ErrorLevel:
+		if l, ok := logger.(errorMapLogger); ok {
+			l.ErrorMap(ctx, dataMap)
+			return
+		}
+	}
+
+	fields := logrus.Fields{}
+	if data, ok := ctx.Value(log.ContextDataMapKey).(map[string]string); ok {
+		for key, value := range data {
+			fields[key] = value
+		}
+	}
+	for key, value := range dataMap {
+		fields[key] = value
+	}
+
+	logger.GetEntry().WithFields(fields).Log(level)
+}
@@ -0,0 +1,101 @@
+package httpmiddleware
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// droppedFieldsPriority are the fields dropped first, in order, when the entry exceeds MaxLogBytes
+var droppedFieldsPriority = []string{
+	FieldResponseBody,
+	FieldReqBody,
+	FieldPanicStack,
+	FieldResponseHeader,
+	FieldReqHeader,
+	FieldResponseTrailer,
+	FieldReqTrailer,
+	FieldQueryParams,
+	FieldRouteParams,
+}
+
+// keptFields are never dropped, so the entry is still useful when it is truncated
+var keptFields = map[string]bool{
+	FieldType:         true,
+	FieldURL:          true,
+	FieldMethod:       true,
+	FieldPath:         true,
+	FieldStatus:       true,
+	FieldDurationMs:   true,
+	FieldDurationUs:   true,
+	FieldDurationNs:   true,
+	FieldReqTimestamp: true,
+}
+
+// limitLogSize is to drop the lower priority fields of the data map until its estimated JSON size fits
+// maxBytes. The dropped fields are listed in FieldTruncatedFields. The fields not in droppedFieldsPriority
+// are dropped afterwards in key order, the keptFields are never dropped
+func limitLogSize(dataMap map[string]interface{}, maxBytes int) {
+	if maxBytes <= 0 {
+		return
+	}
+
+	sizes := make(map[string]int, len(dataMap))
+	total := 0
+	for key, value := range dataMap {
+		sizes[key] = fieldSize(key, value)
+		total += sizes[key]
+	}
+	if total <= maxBytes {
+		return
+	}
+
+	candidates := make([]string, 0, len(dataMap))
+	for _, key := range droppedFieldsPriority {
+		if _, ok := dataMap[key]; ok {
+			candidates = append(candidates, key)
+		}
+	}
+	others := make([]string, 0, len(dataMap))
+	for key := range dataMap {
+		if !keptFields[key] && !contains(droppedFieldsPriority, key) {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	candidates = append(candidates, others...)
+
+	var truncatedFields []string
+	for _, key := range candidates {
+		if total+fieldSize(FieldTruncatedFields, truncatedFields) <= maxBytes {
+			break
+		}
+
+		delete(dataMap, key)
+		total -= sizes[key]
+		truncatedFields = append(truncatedFields, key)
+	}
+
+	if len(truncatedFields) > 0 {
+		dataMap[FieldTruncatedFields] = truncatedFields
+	}
+}
+
+// fieldSize is to estimate the size of the field in the JSON encoded entry, i.e: "key":value,
+func fieldSize(key string, value interface{}) int {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return len(key)
+	}
+
+	return len(key) + len(encoded) + 4
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
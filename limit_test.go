@@ -0,0 +1,51 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+)
+
+func TestLimitLogSize(t *testing.T) {
+	newDataMap := func() map[string]interface{} {
+		return map[string]interface{}{
+			FieldType:           valueLogTypeIngress,
+			FieldMethod:         http.MethodPost,
+			FieldPath:           "/users",
+			FieldStatus:         http.StatusOK,
+			FieldDurationMs:     int64(15),
+			FieldReqBody:        strings.Repeat("a", 100),
+			FieldResponseBody:   strings.Repeat("b", 200),
+			FieldReqHeader:      http.Header{"Accept": []string{"application/json"}},
+			FieldUserAgent:      "curl/7.79.1",
+			FieldClientIP:       "127.0.0.1",
+			FieldResponseHeader: http.Header{},
+		}
+	}
+
+	dataMap := newDataMap()
+	limitLogSize(dataMap, 0)
+	assert.Equal(t, newDataMap(), dataMap)
+
+	total := 0
+	for key, value := range newDataMap() {
+		total += fieldSize(key, value)
+	}
+
+	// dropping the response body is enough
+	dataMap = newDataMap()
+	limitLogSize(dataMap, total-100)
+	assert.Equal(t, []string{FieldResponseBody}, dataMap[FieldTruncatedFields])
+	assert.Equal(t, strings.Repeat("a", 100), dataMap[FieldReqBody])
+
+	dataMap = newDataMap()
+	limitLogSize(dataMap, 1)
+	assert.Equal(t, []string{
+		FieldResponseBody, FieldReqBody, FieldResponseHeader, FieldReqHeader, FieldClientIP, FieldUserAgent,
+	}, dataMap[FieldTruncatedFields])
+	assert.Equal(t, http.StatusOK, dataMap[FieldStatus])
+	assert.Equal(t, int64(15), dataMap[FieldDurationMs])
+	assert.Equal(t, "/users", dataMap[FieldPath])
+}
@@ -3,23 +3,33 @@ package httpmiddleware
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
 	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/muhammad-fakhri/log"
 
-	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
 )
 
 // IngressLog represents concrete type of the middleware
 type IngressLog struct {
+	stats  requestStats
 	logger log.Logger
-	config *Config
+	config atomic.Value // *Config, swapped by SetConfig
+
+	accessLogMu sync.Mutex
 }
 
 type IngressLogger interface {
@@ -27,76 +37,155 @@ type IngressLogger interface {
 	EnforceWithParams(next httprouter.Handle) httprouter.Handle
 }
 
-// LogMessage is a struct to keep the log message easier
+// LogMessage is a struct to keep the log message easier, it holds the logged values of a handled request
 type LogMessage struct {
-	URL            string
+	URL            string // the request URL, without the query string when the query params are logged separately
 	ReqMethod      string
-	ReqHeader      http.Header
+	ReqHeader      http.Header // nil when the request header is not logged
 	ReqBody        string
-	ResponseHeader http.Header
+	ResponseHeader http.Header // nil when the response header is not logged
 	ResponseCode   int
 	ResponseBody   string
 	TimeTakenInMS  int64
+	Duration       time.Duration // the precise TimeTakenInMS
+
+	Path         string
+	Route        string
+	Proto        string
+	ReqTimestamp time.Time
+	ReqSize      int
+	ResponseSize int
+	Error        string
+	Level        logrus.Level
+	// Fields are the other logged fields keyed by the field name, e.g: FieldClientIP, FieldSlow, and the ContextFields
+	Fields map[string]interface{}
+
+	logURL          bool
+	logReqBody      bool
+	logResponseBody bool
+	timestampFormat TimestampFormat
+	durationUnit    DurationUnit
+	flattenHeaders  bool
 }
 
 const (
-	valueLogTypeIngress = "ingress_http"
+	valueLogTypeIngress      = "ingress_http"
+	valueLogTypeIngressStart = "ingress_http_start"
 )
 
 type LogRequest struct {
-	URL    string
-	Method string
-	Header http.Header
-	Body   string
+	URL   string
+	Path  string
+	Route string
+	// RouteParams are the matched route params e.g: {"id": "123"}, only filled when LogRouteParams is true
+	RouteParams map[string]string
+	Query       url.Values
+	Method      string
+	Header      http.Header
+	Trailer     http.Header // filled once the handler reads the body to the end
+	Body        string
+	BodySize    int
+	RemoteAddr  string
+	Host        string
+	Proto       string
+	TLS         *tls.ConnectionState // nil when the connection is plain HTTP
+	// ContentLengthMismatch is true when the declared Content-Length differs from the read body size
+	ContentLengthMismatch bool
+	// BodyReadError is the error reading the request body to be logged, e.g: the client aborts the upload
+	BodyReadError error
+	// BodyReadDuration is the time spent reading the request body to be logged, e.g: to tell a slow client apart
+	BodyReadDuration time.Duration
+
+	bodyRead           bool        // true: the request body is read to be logged
+	bodyCaptureSkipped bool        // true: the request/response bodies are not captured due to MaxConcurrentBodyCapture
+	bodyDigest         *bodyDigest // the digest of the body longer than the capture limit, see HashOversizedBody
+	bodyTail           *bodyTail   // the tail of the body longer than the capture limit, see HeadTailBytes
+	rawBody            []byte      // the completely captured body as it is received, nil when it is not captured
 }
 
-// NewIngressLogMiddleware is to initialize ingress log middleware object
-func NewIngressLogMiddleware(logger log.Logger, optionalConfig ...*Config) *IngressLog {
-	var conf *Config
-	if len(optionalConfig) == 0 || optionalConfig[0] == nil {
-		conf = defaultConfig()
-	} else {
-		conf = NewConfig(optionalConfig[0])
-	}
+// LogResponse is the response of a handled request to be logged
+type LogResponse struct {
+	Status    int
+	Header    http.Header
+	Body      string
+	BodySize  int
+	TimedOut  bool
+	Streaming bool
+	// BodyTruncated is true when the response body is longer than the capture limit, Body only holds its beginning
+	BodyTruncated bool
+	// PanicStack is the stack trace of the recovered handler panic, only captured when LogPanicStack is true
+	PanicStack string
+	// DoubleWriteHeader is true when the handler calls WriteHeader more than once
+	DoubleWriteHeader bool
+	// Fields are added by the handler through AddLogField
+	Fields map[string]interface{}
+
+	bodyDigest *bodyDigest // the digest of the body longer than the capture limit, see HashOversizedBody
+	bodyTail   *bodyTail   // the tail of the body longer than the capture limit, see HeadTailBytes
+}
 
-	return &IngressLog{
-		logger: logger,
-		config: conf,
+// NewIngressLogMiddleware is to initialize ingress log middleware object, the options are either
+// a *Config or functional options e.g: WithSkipPaths("/healthz"), applied in the given order
+func NewIngressLogMiddleware(logger log.Logger, opts ...Option) *IngressLog {
+	conf := defaultConfig()
+	for _, opt := range opts {
+		if opt != nil {
+			conf = opt.apply(conf)
+		}
 	}
+
+	ingressLog := &IngressLog{logger: logger}
+	ingressLog.storeConfig(NewConfig(conf))
+	return ingressLog
 }
 
-// Enforce is to apply log ingress middleware to the 'next' handler
-func (i *IngressLog) Enforce(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logReqMessage := buildLogRequest(r)
+// SetConfig is to replace the middleware config at runtime, e.g: to enable body logging during an incident.
+// It is safe to be called concurrently with the requests being handled, each request uses the config read
+// when it starts. A copy of config is used, so the given config can be modified afterwards. The current
+// config is kept when a pattern of the config is invalid
+func (i *IngressLog) SetConfig(config *Config) error {
+	if config == nil {
+		return nil
+	}
 
-		newRequest := i.appendContextDataAndSetValue(r, i.logger)
-		newWriter := i.logger.CreateResponseWrapper(w)
+	config = config.clone()
+	if err := config.prepare(); err != nil {
+		return err
+	}
 
-		var (
-			startTime       time.Time
-			elapsedTimeInMS int64
-		)
+	i.storeConfig(config)
+	return nil
+}
 
-		defer func(ctx context.Context, request *LogRequest, elapsedTimeInMS *int64, requestTimestamp *time.Time, writer *log.LoggingResponseWriter) {
-			r := recover()
-			if r != nil {
-				fmt.Println("[ingress][panic] recovered from: ", r)
-				debug.PrintStack()
+// storeConfig is to prepare the per-middleware state of the config, then use it for the following requests.
+// The hostname is resolved once, not per request
+func (i *IngressLog) storeConfig(config *Config) {
+	config.serverID = resolveServerID(config.ServerID)
+	if config.MaxConcurrentBodyCapture > 0 {
+		config.bodyCaptureSem = make(chan struct{}, config.MaxConcurrentBodyCapture)
+	}
 
-				// default panic value
-				writer.WriteHeader(http.StatusInternalServerError)
-				writer.Write([]byte(fmt.Sprintf("panic: %v.", r)))
-			}
+	i.config.Store(config)
+}
 
-			i.log(newRequest.Context(), request, *elapsedTimeInMS, *requestTimestamp, writer)
+// resolveServerID is to get the server id logged in every entry, the hostname when serverID is empty
+func resolveServerID(serverID string) string {
+	if len(serverID) > 0 {
+		return serverID
+	}
 
-		}(newRequest.Context(), logReqMessage, &elapsedTimeInMS, &startTime, newWriter)
+	hostname, _ := os.Hostname()
+	return hostname
+}
 
-		startTime = time.Now()
-		next.ServeHTTP(newWriter, newRequest)
-		elapsedTimeInMS = time.Since(startTime).Milliseconds()
+func (i *IngressLog) getConfig() *Config {
+	return i.config.Load().(*Config)
+}
 
+// Enforce is to apply log ingress middleware to the 'next' handler
+func (i *IngressLog) Enforce(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i.serve(w, r, "", nil, nil, next.ServeHTTP)
 	})
 }
 
@@ -104,131 +193,435 @@ func (i *IngressLog) Enforce(next http.Handler) http.Handler {
 // but has a third parameter for the values of wildcards (variables), e.g: github.com/julienschmidt/httprouter
 func (i *IngressLog) EnforceWithParams(next httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		logReqMessage := buildLogRequest(r)
+		i.serve(w, r, routeTemplate(r.URL.Path, ps), ps, nil, func(w http.ResponseWriter, r *http.Request) {
+			next(w, r, ps)
+		})
+	}
+}
 
-		newRequest := i.appendContextDataAndSetValue(r, i.logger)
-		newWriter := i.logger.CreateResponseWrapper(w)
+// EnforceWithRoute is like EnforceWithParams, but logs the given route pattern e.g: "/users/:id"
+// instead of deriving it from the params
+func (i *IngressLog) EnforceWithRoute(route string, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		i.serve(w, r, route, ps, nil, func(w http.ResponseWriter, r *http.Request) {
+			next(w, r, ps)
+		})
+	}
+}
 
-		var (
-			startTime       time.Time
-			elapsedTimeInMS int64
-		)
+// EnforceWithRouteFunc is like Enforce, but logs the route pattern resolved by routeFunc after the 'next'
+// handler returns, e.g: for routers matching the route after their middlewares run. It takes precedence
+// over RouteNameExtractor
+func (i *IngressLog) EnforceWithRouteFunc(routeFunc func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i.serve(w, r, "", nil, routeFunc, next.ServeHTTP)
+	})
+}
 
-		defer func(ctx context.Context, reqmes *LogRequest, elapsedTimeInMS *int64, requestTimestamp *time.Time, writer *log.LoggingResponseWriter) {
-			r := recover()
-			if r != nil {
-				fmt.Println("[ingress][panic] recovered from: ", r)
-				debug.PrintStack()
+// serve is to call the 'next' handler with the request context data and response wrapper, then log it.
+// The route is the matched route pattern of the request, if any, otherwise it is resolved by routeFunc
+// or RouteNameExtractor after the handler returns. The params are the matched route params, if any
+func (i *IngressLog) serve(w http.ResponseWriter, r *http.Request, route string, params httprouter.Params, routeFunc func(r *http.Request) string, next http.HandlerFunc) {
+	// the config is read once, so the request is handled and logged with the same config
+	config := i.getConfig()
+	if config.IsSkippedPath(r.URL.Path) {
+		next(w, r)
+		return
+	}
 
-				// default panic value
-				writer.WriteHeader(http.StatusInternalServerError)
-				writer.Write([]byte(fmt.Sprintf("panic: %v.", r)))
+	// the bodies are not captured when MaxConcurrentBodyCapture requests are already capturing theirs,
+	// the route logging neither body takes no capture slot
+	var captureBody, captureSkipped bool
+	if config.logsBody(r.URL.Path) {
+		captureBody = config.acquireBodyCapture()
+		if captureBody {
+			defer config.releaseBodyCapture()
+		}
+		captureSkipped = !captureBody
+	}
+
+	logReqMessage := i.buildLogRequest(config, r, captureBody)
+	logReqMessage.bodyCaptureSkipped = captureSkipped
+	logReqMessage.Route = route
+	if config.LogRouteParams {
+		logReqMessage.RouteParams = routeParams(params)
+	}
+
+	newRequest := i.appendContextDataAndSetValue(config, r, i.logger)
+	if config.ErrorContextKey != nil && newRequest.Context().Value(config.ErrorContextKey) == nil {
+		newRequest = newRequest.WithContext(context.WithValue(newRequest.Context(), config.ErrorContextKey, new(error)))
+	}
+	var newWriter *responseWriter
+	if config.IsStreamingPath(r.URL.Path) {
+		newWriter = newStreamingWriter(w)
+	} else if conf := config.GetRouteConfig(r.URL.Path); !captureBody || conf.DisableIngressLog || !conf.LogResponseBody() || conf.IsNoBodyLogPath(r.URL.Path) {
+		// nothing reads the response body, skip buffering it
+		newWriter = newStatusWriter(w)
+	} else {
+		newWriter = newResponseWriter(w, conf.getMaxBodyCaptureBytes(), conf.HashOversizedBody, conf.HeadTailBytes)
+	}
+
+	if config.EchoRequestIDHeader {
+		newWriter.Header().Set(headerNameRequestID, GetContextID(newRequest.Context()))
+	}
+
+	i.logRequestStart(newRequest.Context(), config, logReqMessage)
+
+	var (
+		startTime   time.Time
+		elapsedTime time.Duration
+	)
+
+	defer func(ctx context.Context, request *LogRequest, elapsedTime *time.Duration, requestTimestamp *time.Time, writer *responseWriter) {
+		r := recover()
+		if r != nil {
+			stack := reportPanic(r)
+			if writer != nil && config.LogPanicStack {
+				writer.PanicStack = string(stack)
 			}
 
-			i.log(newRequest.Context(), reqmes, *elapsedTimeInMS, *requestTimestamp, writer)
+			if config.RePanicAfterLog {
+				// leave the response to the outer panic handler, only record the status to be logged
+				if writer != nil {
+					writer.Status = http.StatusInternalServerError
+				}
+			} else if writer != nil {
+				i.writePanicResponse(config, writer, r)
+			} else {
+				i.writePanicResponse(config, w, r)
+			}
+		}
+
+		if len(request.Route) == 0 {
+			// the route is resolved after the handler runs, as some routers only know it by then
+			if routeFunc != nil {
+				request.Route = routeFunc(newRequest)
+			} else if config.RouteNameExtractor != nil {
+				request.Route = config.RouteNameExtractor(newRequest)
+			}
+		}
+
+		i.log(ctx, config, request, *elapsedTime, *requestTimestamp, writer)
+		writer.release()
+
+		if r != nil && config.RePanicAfterLog {
+			panic(r)
+		}
+
+	}(newRequest.Context(), logReqMessage, &elapsedTime, &startTime, newWriter)
+
+	startTime = time.Now()
+	if config.HandlerTimeout > 0 && !newWriter.Streaming && newRequest.Context().Value(noHandlerTimeoutKey{}) == nil {
+		i.serveWithTimeout(config, newWriter, newRequest, next)
+	} else {
+		next(newWriter, newRequest)
+	}
+	elapsedTime = time.Since(startTime)
+}
+
+// Recover is a middleware recovering the panic of the 'next' handler without ingress log, the panic is
+// responded with PanicResponse, or plaintext 500 by default, e.g: for the routes logged by another logger
+func (i *IngressLog) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				reportPanic(recovered)
+				i.writePanicResponse(i.getConfig(), w, recovered)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reportPanic is to print the recovered panic and its stack trace to stderr, the stack trace is returned
+func reportPanic(recovered interface{}) []byte {
+	fmt.Println("[ingress][panic] recovered from: ", recovered)
+	stack := debug.Stack()
+	os.Stderr.Write(stack)
 
-		}(newRequest.Context(), logReqMessage, &elapsedTimeInMS, &startTime, newWriter)
+	return stack
+}
 
-		startTime = time.Now()
-		next(newWriter, newRequest, ps)
-		elapsedTimeInMS = time.Since(startTime).Milliseconds()
+// writePanicResponse is to respond the recovered panic with PanicResponse, or plaintext 500 by default
+func (i *IngressLog) writePanicResponse(config *Config, w http.ResponseWriter, recovered interface{}) {
+	if config.PanicResponse == nil {
+		// default panic value
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("panic: %v.", recovered)))
+		return
+	}
 
+	status, contentType, body := config.PanicResponse(recovered)
+	if len(contentType) > 0 {
+		w.Header().Set(headerNameContentType, contentType)
 	}
+	w.WriteHeader(status)
+	w.Write(body)
 }
 
-func (i *IngressLog) log(ctx context.Context, request *LogRequest, timeTaken int64, requestTimestamp time.Time, rw *log.LoggingResponseWriter) {
-	if i.config.DisableIngressLog || (i.config.LogFailedRequestOnly() && rw.Status == http.StatusOK) {
+func (i *IngressLog) log(ctx context.Context, config *Config, request *LogRequest, elapsedTime time.Duration, requestTimestamp time.Time, rw *responseWriter) {
+	// a missing response wrapper degrades to logging the request only
+	response := rw.logResponse()
+	if rw != nil && response.Status == 0 {
+		// net/http responds 200 when the handler returns without calling WriteHeader
+		response.Status = http.StatusOK
+	}
+	i.stats.record(response.Status, elapsedTime)
+	if config.IsSkippedMethod(request.Method) {
+		return
+	}
+
+	if config.AccessLogWriter != nil {
+		i.writeAccessLog(config, request, response, requestTimestamp)
+	}
+
+	conf := config.GetRouteConfig(request.Path)
+	shouldLog := conf.shouldLog(GetContextID(ctx), response.Status)
+	if !shouldLog && config.AfterLog == nil && config.AuditLogger == nil {
 		// skip ingress log, rely on load balancer log or custom log instead
 		return
 	}
 
-	// construct data map
-	dataMap := make(map[string]interface{})
-	dataMap[FieldType] = valueLogTypeIngress
-	dataMap[FieldURL] = fmt.Sprintf("%s %s", request.Method, request.URL)
-	dataMap[FieldReqTimestamp] = requestTimestamp.Unix()
-	dataMap[FieldStatus] = rw.Status
-	dataMap[FieldDurationMs] = timeTaken
-
-	if i.config.LogRequestHeader() {
-		header := request.Header.Clone()
-		header.Del("Authorization")
-
-		excludeRequestHeaderKeys := i.config.ExcludeOpt.RequestHeaderKeys
-		if len(excludeRequestHeaderKeys) > 0 {
-			for _, headerKey := range excludeRequestHeaderKeys {
-				header.Del(headerKey)
-			}
+	msg := conf.BuildLogMessage(ctx, request, response, elapsedTime, requestTimestamp)
+	if len(config.serverID) > 0 {
+		msg.Fields[FieldServerID] = config.serverID
+	}
+	mergeFields(msg.Fields, config.CommonFields)
+	shouldAudit := config.AuditLogger != nil && (config.AuditPredicate == nil || config.AuditPredicate(msg))
+	if shouldLog || shouldAudit {
+		dataMap := msg.dataMap()
+		limitLogSize(dataMap, conf.MaxLogBytes)
+		dataMap = conf.renameFields(dataMap)
+		if shouldLog {
+			i.emit(ctx, config, msg.Level, dataMap)
 		}
+		if shouldAudit {
+			logMap(ctx, config.AuditLogger, msg.Level, dataMap)
+		}
+	}
 
-		dataMap[FieldReqHeader] = header
+	if config.AfterLog != nil {
+		config.AfterLog(ctx, msg)
+	}
+}
+
+// logRequestStart is to log the incoming request before it is handled, so in-flight requests are traceable
+func (i *IngressLog) logRequestStart(ctx context.Context, config *Config, request *LogRequest) {
+	conf := config.GetRouteConfig(request.Path)
+	if !conf.LogOnRequestStart || conf.DisableIngressLog {
+		return
 	}
 
-	if i.config.LogRequestBody() {
-		dataMap[FieldReqBody] = request.Body
+	dataMap := make(map[string]interface{})
+	dataMap[FieldType] = valueLogTypeIngressStart
+	conf.appendURLFields(dataMap, request)
+	dataMap[FieldReqSize] = request.BodySize
+	if len(config.serverID) > 0 {
+		dataMap[FieldServerID] = config.serverID
+	}
+
+	if conf.LogQueryParams {
+		dataMap[FieldQueryParams] = conf.formatQueryParams(request.Query)
 	}
 
-	if i.config.LogResponseHeader() {
-		header := rw.Header().Clone()
-		header.Del("Authorization")
-		dataMap[FieldResponseHeader] = header
+	if conf.LogRequestHeader() {
+		dataMap[FieldReqHeader] = headerValue(conf.formatRequestHeader(request.Header), conf.FlattenHeaders)
 	}
 
-	if i.config.LogResponseBody() {
-		if i.config.LogSuccessResponseBody() {
-			dataMap[FieldResponseBody] = rw.Body
+	if conf.LogRequestBody() {
+		if conf.IsNoBodyLogPath(request.Path) {
+			dataMap[FieldReqBody] = wipedMessage
 		} else {
-			if rw.Status != http.StatusOK {
-				dataMap[FieldResponseBody] = rw.Body
-			} else {
-				dataMap[FieldResponseBody] = wipedMessage
-			}
+			dataMap[FieldReqBody] = conf.formatBody(request.Header.Get(headerNameContentType), request.Body)
 		}
 	}
 
-	i.logger.InfoMap(ctx, dataMap)
+	mergeFields(dataMap, config.CommonFields)
+	i.emit(ctx, config, logrus.InfoLevel, conf.renameFields(dataMap))
+}
+
+// emit is to write the ingress log entry to LogSink when it is set, otherwise to the logger
+func (i *IngressLog) emit(ctx context.Context, config *Config, level logrus.Level, dataMap map[string]interface{}) {
+	if config.LogSink != nil {
+		config.LogSink(ctx, dataMap)
+		return
+	}
 
+	logMap(ctx, i.logger, level, dataMap)
 }
 
-func buildLogRequest(r *http.Request) *LogRequest {
+// mergeFields is to add the fields into the data map without overriding the existing ones
+func mergeFields(dataMap map[string]interface{}, fields map[string]interface{}) {
+	for key, value := range fields {
+		if _, ok := dataMap[key]; !ok {
+			dataMap[key] = value
+		}
+	}
+}
+
+// buildLogRequest is to capture the request to be logged, the body is read only when captureBody is true
+func (i *IngressLog) buildLogRequest(config *Config, r *http.Request, captureBody bool) *LogRequest {
+	var (
+		body                  string
+		bodySize              int
+		contentLengthMismatch bool
+		bodyReadErr           error
+		bodyReadDuration      time.Duration
+		bodyRead              bool
+		digest                *bodyDigest
+		tail                  *bodyTail
+		rawBody               []byte
+	)
+
+	conf := config.GetRouteConfig(r.URL.Path)
+	if captureBody && conf.LogRequestBody() && !conf.IsNoBodyLogPath(r.URL.Path) {
+		readStart := time.Now()
+		body, rawBody, bodySize, bodyReadErr = conf.getRequestBody(r, conf.getMaxBodyCaptureBytes())
+		bodyReadDuration, bodyRead = time.Since(readStart), true
+		// the declared size can only be verified when the body is read, -1 means unknown
+		contentLengthMismatch = bodyReadErr == nil && r.ContentLength >= 0 && int64(bodySize) != r.ContentLength
+		if bodyReadErr == nil && conf.isOversizedBody(bodySize) {
+			// only the beginning of the body is captured, it is hashed as the handler reads it
+			digest = digestBody(&r.Body)
+		}
+		if bodyReadErr == nil && rawBody == nil && conf.HeadTailBytes > 0 {
+			// only the beginning of the body is captured, its tail is kept as the handler reads it
+			tail = tailBody(&r.Body, conf.HeadTailBytes)
+		}
+	} else if r.ContentLength > 0 {
+		// leave the body untouched for the handler to stream, rely on the declared size instead
+		bodySize = int(r.ContentLength)
+	}
+
 	return &LogRequest{
-		URL:    r.URL.String(),
-		Method: r.Method,
-		Header: r.Header,
-		Body:   getRequestBody(r),
+		URL:        r.URL.String(),
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Method:     r.Method,
+		Header:     r.Header,
+		Trailer:    r.Trailer,
+		Body:       body,
+		BodySize:   bodySize,
+		RemoteAddr: r.RemoteAddr,
+		Host:       r.Host,
+		Proto:      r.Proto,
+		TLS:        r.TLS,
+
+		ContentLengthMismatch: contentLengthMismatch,
+		BodyReadError:         bodyReadErr,
+		BodyReadDuration:      bodyReadDuration,
+
+		bodyRead:   bodyRead,
+		bodyDigest: digest,
+		bodyTail:   tail,
+		rawBody:    rawBody,
+	}
+}
+
+// getScheme is to get the scheme of the original request, the X-Forwarded-Proto header set by the proxy
+// is preferred over the connection
+func getScheme(request *LogRequest) string {
+	if forwardedProto := request.Header.Get(headerNameForwardedProto); forwardedProto != "" {
+		return strings.ToLower(strings.TrimSpace(strings.Split(forwardedProto, ",")[0]))
 	}
+
+	if request.TLS != nil {
+		return schemeHTTPS
+	}
+
+	return schemeHTTP
 }
 
-func getRequestBody(request *http.Request) string {
+// getRequestBody is to get the logged request body, the raw body and its size in bytes, the request body stays
+// untouched for the handler even when the logged body is decompressed or summarized. An absent body is empty,
+// the raw body is nil when the body is longer than the limit. The read error is returned e.g: when the client
+// aborts the upload
+func (c *Config) getRequestBody(request *http.Request, limit int) (string, []byte, int, error) {
 	if request.Body == nil {
-		return "null"
+		return "", []byte{}, 0, nil
 	}
 
-	requestBodyBytes, err := getBodyBytes(&request.Body)
+	requestBodyBytes, complete, err := getBodyBytes(&request.Body, limit)
 	if err != nil {
-		return "null"
+		return "", nil, 0, err
+	}
+
+	bodySize := len(requestBodyBytes)
+	if !complete {
+		// the remaining body is left unread for the handler, rely on the declared size instead
+		if int(request.ContentLength) > bodySize {
+			bodySize = int(request.ContentLength)
+		}
+		return string(requestBodyBytes) + truncatedMessage, nil, bodySize, nil
 	}
 
-	return string(requestBodyBytes)
+	loggedBody := requestBodyBytes
+	if c.DecodeCompressedBody {
+		var truncated bool
+		loggedBody, truncated = decodeBody(request.Header.Get(headerNameContentEncoding), loggedBody, limit)
+		if truncated {
+			// the partially decompressed body is neither summarized nor parsed
+			return string(loggedBody) + truncatedMessage, requestBodyBytes, bodySize, nil
+		}
+	}
+
+	if summary, ok := summarizeMultipartBody(request.Header.Get(headerNameContentType), loggedBody); ok {
+		return summary, requestBodyBytes, bodySize, nil
+	}
+
+	if c.ParseFormBody {
+		if form, ok := c.formatFormBody(request.Header.Get(headerNameContentType), loggedBody); ok {
+			return form, requestBodyBytes, bodySize, nil
+		}
+	}
+
+	return string(loggedBody), requestBodyBytes, bodySize, nil
 }
 
-func getBodyBytes(body *io.ReadCloser) ([]byte, error) {
-	responseBodyBytes, err := ioutil.ReadAll(*body)
-	*body = ioutil.NopCloser(bytes.NewBuffer(responseBodyBytes))
-	return responseBodyBytes, err
+// getBodyBytes is to read up to limit bytes of the body, then restore the body stream by concatenating the read
+// bytes with the unread remainder. Complete is false when the body is longer than the limit
+func getBodyBytes(body *io.ReadCloser, limit int) (bodyBytes []byte, complete bool, err error) {
+	original := *body
+	// one more byte than the limit is read to tell whether the body is longer than the limit
+	readLimit := int64(limit) + 1
+	if readLimit <= 0 {
+		readLimit = math.MaxInt64
+	}
+	readBytes, err := ioutil.ReadAll(io.LimitReader(original, readLimit))
+	*body = &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(readBytes), original),
+		Closer: original,
+	}
+
+	if len(readBytes) > limit {
+		return readBytes[:limit], false, err
+	}
+	return readBytes, true, err
+}
+
+// readCloser is a body reading from the Reader and closing the Closer
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
-func (i *IngressLog) appendContextDataAndSetValue(r *http.Request, l log.Logger) *http.Request {
+func (i *IngressLog) appendContextDataAndSetValue(config *Config, r *http.Request, l log.Logger) *http.Request {
 	v := r.Context().Value(log.ContextDataMapKey)
 	if v != nil {
 		return r
 	}
 
 	var contextID string
-	if contextID = r.Header.Get(headerNameRequestID); contextID == "" {
-		contextID = uuid.New().String()
+	for _, headerName := range config.GetRequestIDHeaders() {
+		if contextID = r.Header.Get(headerName); contextID != "" {
+			break
+		}
+	}
+
+	if contextID == "" {
+		contextID = config.generateID()
 	}
 
-	// TODO: add common fields to be logged in http
 	return l.SetContextDataAndSetValue(r, nil, contextID)
 }
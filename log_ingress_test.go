@@ -4,12 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/c2fo/testify/assert"
@@ -45,6 +52,9 @@ func getMockServerWithConfig(logger log.Logger, config *Config) *httptest.Server
 	mux.Handle("/hello", logIngresssMiddleware.Enforce(http.HandlerFunc(hello)))
 	mux.Handle("/exclude-options-success", logIngresssMiddleware.Enforce(http.HandlerFunc(excludeOptionsSuccessHandler)))
 	mux.Handle("/exclude-options-error", logIngresssMiddleware.Enforce(http.HandlerFunc(excludeOptionsErrorHandler)))
+	mux.Handle("/echo", logIngresssMiddleware.Enforce(http.HandlerFunc(echoHandler)))
+	mux.Handle("/status", logIngresssMiddleware.Enforce(http.HandlerFunc(statusHandler)))
+	mux.Handle("/slow", logIngresssMiddleware.Enforce(http.HandlerFunc(slowHandler)))
 
 	mockServer := httptest.NewServer(mux)
 	return mockServer
@@ -63,6 +73,30 @@ func hello(writer http.ResponseWriter, request *http.Request) {
 	writer.Write(responseBodyBytes) // to match it with the request body
 }
 
+func echoHandler(writer http.ResponseWriter, request *http.Request) {
+	// read body
+	responseBodyBytes, _ := ioutil.ReadAll(request.Body)
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(responseBodyBytes) // to match it with the request body
+}
+
+// statusHandler responds with the status code given in the "code" query param
+func statusHandler(writer http.ResponseWriter, request *http.Request) {
+	code, _ := strconv.Atoi(request.URL.Query().Get("code"))
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+	writer.Write([]byte(`{"code":` + strconv.Itoa(code) + `}`))
+}
+
+func slowHandler(writer http.ResponseWriter, request *http.Request) {
+	time.Sleep(50 * time.Millisecond)
+
+	writer.WriteHeader(http.StatusOK)
+}
+
 func panicHandler(writer http.ResponseWriter, request *http.Request) {
 	time.Sleep(111 * time.Millisecond)
 	testPanic(nil)
@@ -321,9 +355,8 @@ func TestLogIngressMessageExcludeOptionsError(t *testing.T) {
 func extractLogMessage(t *testing.T, mssg logrus.Fields) *LogMessage {
 	logMessage := &LogMessage{}
 
-	urlPath := strings.Split(mssg[FieldURL].(string), " ")
-	logMessage.URL = urlPath[1]
-	logMessage.ReqMethod = urlPath[0]
+	logMessage.URL = strings.TrimPrefix(mssg[FieldURL].(string), mssg[FieldMethod].(string)+" ")
+	logMessage.ReqMethod = mssg[FieldMethod].(string)
 	logMessage.ResponseCode = mssg[FieldStatus].(int)
 	logMessage.TimeTakenInMS = mssg[FieldDurationMs].(int64)
 	logMessage.ReqHeader = mssg[FieldReqHeader].(http.Header)
@@ -333,49 +366,1201 @@ func extractLogMessage(t *testing.T, mssg logrus.Fields) *LogMessage {
 	return logMessage
 }
 
-func extractHeader(t *testing.T, header string) http.Header {
-	var result = make(http.Header)
-
-	newHead := strings.ReplaceAll(header, "map", "")
-	newHead = strings.ReplaceAll(newHead, "[", "")
-	newHead = strings.ReplaceAll(newHead, "]", "")
+func TestDisableLogIngressMessage(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{DisableIngressLog: true})
+	defer mockServer.Close()
 
-	if newHead == "" {
-		return result
+	reqBody, err := json.Marshal(&requestBody{
+		Name: "shopee-shopee",
+	})
+	if err != nil {
+		t.Error(err)
 	}
 
-	messageComponents := strings.Split(newHead, " ")
+	req, _ := http.NewRequest(http.MethodGet, mockServer.URL+"/hello", bytes.NewReader(reqBody))
+	req.Header.Add("X-Country", "ID")
+	req.Header.Add("Authorization", "Bearer abcdefghijkl")
 
-	for i := 0; i < len(messageComponents); i++ {
-		keyValueMessage := strings.Split(messageComponents[i], ":")
+	client := &http.Client{}
+	_, err = client.Do(req)
 
-		result[keyValueMessage[0]] = []string{keyValueMessage[1]}
-	}
+	time.Sleep(100 * time.Millisecond)
 
-	return result
+	assert.Nil(t, err)
+	assert.Nil(t, hook.LastEntry())
 }
 
-func TestDisableLogIngressMessage(t *testing.T) {
+func TestLogIngressMessageMaxBodyBytes(t *testing.T) {
 	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
-	mockServer := getMockServerWithConfig(logger, &Config{DisableIngressLog: true})
+	mockServer := getMockServerWithConfig(logger, &Config{MaxBodyBytes: 10})
 	defer mockServer.Close()
 
 	reqBody, err := json.Marshal(&requestBody{
-		Name: "shopee-shopee",
+		Name: "long request body need to be truncated",
 	})
 	if err != nil {
 		t.Error(err)
 	}
 
-	req, _ := http.NewRequest(http.MethodGet, mockServer.URL+"/hello", bytes.NewReader(reqBody))
-	req.Header.Add("X-Country", "ID")
+	req, _ := http.NewRequest(http.MethodPost, mockServer.URL+"/echo", bytes.NewReader(reqBody))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	// handler still receives the full body
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, string(reqBody), string(respBody))
+
+	logMessage := extractLogMessage(t, hook.LastEntry().Data)
+	assert.Equal(t, string(reqBody[:10])+truncatedMessage, logMessage.ReqBody)
+	assert.Equal(t, string(reqBody[:10])+truncatedMessage, logMessage.ResponseBody)
+}
+
+func TestLogIngressMessageSensitiveHeaderKeys(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{SensitiveHeaderKeys: []string{"X-Api-Key"}})
+	defer mockServer.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, mockServer.URL+"/echo", nil)
+	req.Header.Add("Authorization", "Bearer abcdefghijkl")
+	req.Header.Add("X-Api-Key", "secret")
+
+	client := &http.Client{}
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	logMessage := extractLogMessage(t, hook.LastEntry().Data)
+	assert.Equal(t, "Bearer abcdefghijkl", logMessage.ReqHeader.Get("Authorization"))
+	assert.Empty(t, logMessage.ReqHeader.Get("X-Api-Key"))
+}
+
+func TestLogIngressMessageSkipPaths(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{SkipPaths: []string{"/echo"}})
+	defer mockServer.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, mockServer.URL+"/echo", nil)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Nil(t, hook.LastEntry())
+}
+
+func TestLogIngressMessageSkipMethods(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{SkipMethods: []string{"options"}})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+	for _, method := range []string{http.MethodOptions, http.MethodGet} {
+		req, _ := http.NewRequest(method, mockServer.URL+"/echo", nil)
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, 1, len(hook.AllEntries()))
+	assert.Equal(t, http.MethodGet, hook.LastEntry().Data[FieldMethod])
+}
+
+func TestLogIngressMessageStatusBasedLogLevel(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{StatusBasedLogLevel: true, DowngradeStatuses: []int{http.StatusUnauthorized}})
+	defer mockServer.Close()
+
+	tests := []struct {
+		code     int
+		expected logrus.Level
+	}{
+		{code: http.StatusOK, expected: logrus.InfoLevel},
+		{code: http.StatusUnauthorized, expected: logrus.InfoLevel},
+		{code: http.StatusNotFound, expected: logrus.WarnLevel},
+		{code: http.StatusBadGateway, expected: logrus.ErrorLevel},
+	}
+
+	client := &http.Client{}
+	for _, tt := range tests {
+		_, err := client.Get(mockServer.URL + "/status?code=" + strconv.Itoa(tt.code))
+		assert.Nil(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Equal(t, tt.expected, hook.LastEntry().Level)
+		assert.Equal(t, tt.code, hook.LastEntry().Data[FieldStatus])
+		assert.True(t, len(hook.LastEntry().Data["context_id"].(string)) > 0)
+	}
+}
+
+func TestGetClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		header     http.Header
+		expected   string
+	}{
+		{
+			name:       "remote address",
+			remoteAddr: "10.0.0.1:54321",
+			header:     http.Header{},
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "forwarded headers from untrusted peer",
+			remoteAddr: "198.51.100.9:54321",
+			header:     http.Header{"X-Forwarded-For": []string{"203.0.113.7"}, "X-Real-Ip": []string{"203.0.113.8"}},
+			expected:   "198.51.100.9",
+		},
+		{
+			name:       "forwarded headers without trusted proxies",
+			remoteAddr: "10.0.0.1:54321",
+			header:     http.Header{"X-Forwarded-For": []string{"203.0.113.7"}},
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "forwarded for chain",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:54321",
+			header:     http.Header{"X-Forwarded-For": []string{"203.0.113.7, 10.0.0.2"}, "X-Real-Ip": []string{"10.0.0.3"}},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "spoofed forwarded for",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:54321",
+			header:     http.Header{"X-Forwarded-For": []string{"1.2.3.4, 203.0.113.7, 10.0.0.2"}},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "single trusted proxy ip",
+			proxies:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:54321",
+			header:     http.Header{"X-Real-Ip": []string{"203.0.113.8"}},
+			expected:   "203.0.113.8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig(&Config{TrustedProxies: tt.proxies})
+			request := &LogRequest{Header: tt.header, RemoteAddr: tt.remoteAddr}
+			assert.Equal(t, tt.expected, config.getClientIP(request))
+		})
+	}
+}
+
+func TestConfigInvalidTrustedProxies(t *testing.T) {
+	assert.Panics(t, func() { NewConfig(&Config{TrustedProxies: []string{"10.0.0.0/33"}}) })
+	assert.Panics(t, func() { NewConfig(&Config{TrustedProxies: []string{"proxy"}}) })
+}
+
+func TestLogIngressMessageHostScheme(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{LogHostScheme: true})
+	handler := middleware.Enforce(http.HandlerFunc(echoHandler))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://tenant.example.com/users", nil))
+	assert.Equal(t, "tenant.example.com", hook.LastEntry().Data[FieldHost])
+	assert.Equal(t, "http", hook.LastEntry().Data[FieldScheme])
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://tenant.example.com/users", nil))
+	assert.Equal(t, "https", hook.LastEntry().Data[FieldScheme])
+
+	req := httptest.NewRequest(http.MethodGet, "http://tenant.example.com/users", nil)
+	req.Header.Set("X-Forwarded-Proto", "HTTPS")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "https", hook.LastEntry().Data[FieldScheme])
+}
+
+func TestLogIngressMessageClientIP(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+	_, err := client.Get(mockServer.URL + "/echo")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "127.0.0.1", hook.LastEntry().Data[FieldClientIP])
+
+	hook.Reset()
+	excludeServer := getMockServerWithConfig(logger, &Config{ExcludeOpt: &ExcludeOption{ClientIP: true}})
+	defer excludeServer.Close()
+
+	_, err = client.Get(excludeServer.URL + "/echo")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	_, ok := hook.LastEntry().Data[FieldClientIP]
+	assert.False(t, ok)
+}
+
+func TestLogIngressMessageBodySize(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{})
+	defer mockServer.Close()
+
+	reqBody := `{"name":"shopee"}`
+	req, _ := http.NewRequest(http.MethodPost, mockServer.URL+"/echo", bytes.NewReader([]byte(reqBody)))
+
+	client := &http.Client{}
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, len(reqBody), hook.LastEntry().Data[FieldReqSize])
+	assert.Equal(t, len(reqBody), hook.LastEntry().Data[FieldResponseSize])
+}
+
+func TestLogIngressMessageLoggableContentTypes(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{LoggableContentTypes: []string{"text/"}})
+	defer mockServer.Close()
+
+	reqBody := "plain text body"
+	req, _ := http.NewRequest(http.MethodPost, mockServer.URL+"/echo", bytes.NewReader([]byte(reqBody)))
+	req.Header.Set("Content-Type", "text/plain")
+
+	client := &http.Client{}
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	logMessage := extractLogMessage(t, hook.LastEntry().Data)
+	assert.Equal(t, reqBody, logMessage.ReqBody)
+	// echo handler responds with application/json
+	assert.Equal(t, wipedMessage, logMessage.ResponseBody)
+}
+
+func TestLogIngressMessageRouteConfig(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{
+		RouteConfig: map[string]*Config{
+			"/echo": {ExcludeOpt: &ExcludeOption{RequestBody: true, ResponseBody: true}},
+		},
+	})
+	defer mockServer.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, mockServer.URL+"/echo", bytes.NewReader([]byte(`{"name":"shopee"}`)))
+
+	client := &http.Client{}
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, hasReqBody := hook.LastEntry().Data[FieldReqBody]
+	_, hasResponseBody := hook.LastEntry().Data[FieldResponseBody]
+	assert.False(t, hasReqBody)
+	assert.False(t, hasResponseBody)
+	assert.NotNil(t, hook.LastEntry().Data[FieldReqHeader])
+}
+
+func TestLogIngressMessageRouteConfigKeepsMasking(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{
+		ExcludeOpt: &ExcludeOption{MaskBodyFields: []string{"password"}},
+		RouteConfig: map[string]*Config{
+			"/login": {ExcludeOpt: &ExcludeOption{ResponseBody: true}},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"name":"shopee","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	middleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	// the route only excludes the response body, the base masking still applies
+	assert.Equal(t, `{"name":"shopee","password":"-"}`, hook.LastEntry().Data[FieldReqBody])
+	_, hasResponseBody := hook.LastEntry().Data[FieldResponseBody]
+	assert.False(t, hasResponseBody)
+}
+
+func TestLogIngressMessageLogOnRequestStart(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{LogOnRequestStart: true})
+	defer mockServer.Close()
+
+	reqBody := `{"name":"shopee"}`
+	req, _ := http.NewRequest(http.MethodPost, mockServer.URL+"/echo", bytes.NewReader([]byte(reqBody)))
 	req.Header.Add("Authorization", "Bearer abcdefghijkl")
 
 	client := &http.Client{}
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	entries := hook.AllEntries()
+	assert.Equal(t, 2, len(entries))
+
+	startEntry := entries[0]
+	assert.Equal(t, valueLogTypeIngressStart, startEntry.Data[FieldType])
+	assert.Equal(t, http.MethodPost+" /echo", startEntry.Data[FieldURL])
+	assert.Equal(t, reqBody, startEntry.Data[FieldReqBody])
+	assert.Empty(t, startEntry.Data[FieldReqHeader].(http.Header).Get("Authorization"))
+	assert.Equal(t, entries[1].Data["context_id"], startEntry.Data["context_id"])
+
+	assert.Equal(t, valueLogTypeIngress, entries[1].Data[FieldType])
+}
+
+func TestLogIngressMessageSlowRequest(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{SlowRequestThresholdMs: 20, SlowRequestWarn: true})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+	_, err := client.Get(mockServer.URL + "/slow")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, true, hook.LastEntry().Data[FieldSlow])
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+
+	_, err = client.Get(mockServer.URL + "/echo")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, ok := hook.LastEntry().Data[FieldSlow]
+	assert.False(t, ok)
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+}
+
+func TestLogIngressMessageQueryParams(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{
+		LogQueryParams:     true,
+		SensitiveQueryKeys: []string{"token"},
+	})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+	_, err := client.Get(mockServer.URL + "/echo?page=2&page=3&Token=abcdefghijkl")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, http.MethodGet+" /echo", hook.LastEntry().Data[FieldURL])
+
+	queryParams := hook.LastEntry().Data[FieldQueryParams].(url.Values)
+	assert.Equal(t, []string{"2", "3"}, queryParams["page"])
+	assert.Equal(t, []string{wipedMessage}, queryParams["Token"])
+}
+
+func TestRequestIDHeaders(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{RequestIDHeaders: []string{"X-Correlation-ID", "X-Request-ID"}})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+
+	req, _ := http.NewRequest(http.MethodGet, mockServer.URL+"/echo", nil)
+	req.Header.Add("X-Request-ID", "request-id")
+	req.Header.Add("X-Correlation-ID", "correlation-id")
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "correlation-id", hook.LastEntry().Data["context_id"])
+
+	req, _ = http.NewRequest(http.MethodGet, mockServer.URL+"/echo", nil)
+	req.Header.Add("X-Request-ID", "request-id")
 	_, err = client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "request-id", hook.LastEntry().Data["context_id"])
+}
+
+func TestRequestIDGenerator(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{IDGenerator: func() string { return "4fZk2LmQ9xPa" }})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+
+	_, err := client.Get(mockServer.URL + "/echo")
+	assert.Nil(t, err)
 
 	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "4fZk2LmQ9xPa", hook.LastEntry().Data["context_id"])
 
+	req, _ := http.NewRequest(http.MethodGet, mockServer.URL+"/echo", nil)
+	req.Header.Add("X-Request-ID", "request-id")
+	_, err = client.Do(req)
 	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "request-id", hook.LastEntry().Data["context_id"])
+}
+
+func TestEchoRequestIDHeader(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{EchoRequestIDHeader: true})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+	resp, err := client.Get(mockServer.URL + "/echo")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	contextID := hook.LastEntry().Data["context_id"].(string)
+	assert.True(t, len(contextID) > 0)
+	assert.Equal(t, contextID, resp.Header.Get("X-Request-ID"))
+
+	req, _ := http.NewRequest(http.MethodGet, mockServer.URL+"/echo", nil)
+	req.Header.Add("X-Request-ID", "incoming-request-id")
+	resp, err = client.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "incoming-request-id", resp.Header.Get("X-Request-ID"))
+}
+
+func TestLogIngressMessageContextFields(t *testing.T) {
+	type contextKey string
+
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{
+		ContextFields: func(ctx context.Context) map[string]interface{} {
+			return map[string]interface{}{
+				"user_id":   ctx.Value(contextKey("user_id")),
+				FieldStatus: "overridden",
+			}
+		},
+	})
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextKey("user_id"), "user-1")
+		logIngressMiddleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(w, r.WithContext(ctx))
+	}
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/echo", nil))
+
+	assert.Equal(t, "user-1", hook.LastEntry().Data["user_id"])
+	assert.Equal(t, http.StatusOK, hook.LastEntry().Data[FieldStatus])
+}
+
+func TestLogIngressMessageExcludeResponseBodyStatuses(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{ExcludeResponseBodyStatuses: []int{http.StatusForbidden}})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+	_, err := client.Get(mockServer.URL + "/status?code=403")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldResponseBody])
+
+	_, err = client.Get(mockServer.URL + "/status?code=400")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, `{"code":400}`, hook.LastEntry().Data[FieldResponseBody])
+}
+
+func TestLogIngressMessageExcludeSuccessResponseBody2xx(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{
+		ExcludeOpt: &ExcludeOption{SuccessResponseBody: true},
+	})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+	for _, code := range []int{http.StatusCreated, http.StatusNoContent} {
+		_, err := client.Get(mockServer.URL + "/status?code=" + strconv.Itoa(code))
+		assert.Nil(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Equal(t, code, hook.LastEntry().Data[FieldStatus])
+		assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldResponseBody])
+	}
+
+	_, err := client.Get(mockServer.URL + "/status?code=300")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, `{"code":300}`, hook.LastEntry().Data[FieldResponseBody])
+}
+
+func TestLogIngressMessageErrorContextKey(t *testing.T) {
+	type contextKey string
+	errorKey := contextKey("error")
+
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{ErrorContextKey: errorKey})
+
+	failedHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*r.Context().Value(errorKey).(*error) = errors.New("user not found")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	logIngressMiddleware.Enforce(failedHandler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	assert.Equal(t, "user not found", hook.LastEntry().Data[FieldError])
+
+	logIngressMiddleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/echo", nil))
+	_, ok := hook.LastEntry().Data[FieldError]
+	assert.False(t, ok)
+
+	ctx := context.WithValue(context.Background(), errorKey, errors.New("upstream error"))
+	req := httptest.NewRequest(http.MethodGet, "/echo", nil).WithContext(ctx)
+	logIngressMiddleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "upstream error", hook.LastEntry().Data[FieldError])
+}
+
+func TestBuildLogRequestExcludeRequestBody(t *testing.T) {
+	logger := log.NewLogger("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{
+		ExcludeOpt: &ExcludeOption{RequestBody: true},
+	})
+
+	body := ioutil.NopCloser(strings.NewReader(`{"name":"shopee"}`))
+	req := httptest.NewRequest(http.MethodPost, "/upload", nil)
+	req.Body = body
+	req.ContentLength = 17
+
+	logRequest := logIngressMiddleware.buildLogRequest(logIngressMiddleware.getConfig(), req, true)
+	assert.Empty(t, logRequest.Body)
+	assert.Equal(t, 17, logRequest.BodySize)
+	// the body is not read nor replaced
+	assert.Equal(t, body, req.Body)
+}
+
+func TestLogMessageResponsePanicCustomResponse(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{
+		PanicResponse: func(recovered interface{}) (int, string, []byte) {
+			body, _ := json.Marshal(map[string]string{"error": fmt.Sprint(recovered)})
+			return http.StatusServiceUnavailable, "application/json", body
+		},
+	})
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("database is down")
+	})
+
+	recorder := httptest.NewRecorder()
+	logIngressMiddleware.Enforce(panicking).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":"database is down"}`, recorder.Body.String())
+
+	assert.Equal(t, http.StatusServiceUnavailable, hook.LastEntry().Data[FieldStatus])
+	assert.Equal(t, `{"error":"database is down"}`, hook.LastEntry().Data[FieldResponseBody])
+}
+
+func TestLogIngressMessageServerID(t *testing.T) {
+	hostname, _ := os.Hostname()
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger)
+	handler := logIngressMiddleware.Enforce(http.HandlerFunc(echoHandler))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, hostname, hook.LastEntry().Data[FieldServerID])
+
+	logIngressMiddleware.SetConfig(&Config{ServerID: "pod-1"})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, "pod-1", hook.LastEntry().Data[FieldServerID])
+}
+
+func TestLogIngressMessageCommonFields(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{
+		LogOnRequestStart: true,
+		CommonFields:      map[string]interface{}{"service": "checkout", "tenant": "default", FieldStatus: "overridden"},
+	})
+	handler := logIngressMiddleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddLogField(w, "tenant", "shopee")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	entries := hook.AllEntries()
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "checkout", entries[0].Data["service"])
+	assert.Equal(t, "checkout", entries[1].Data["service"])
+	assert.Equal(t, "shopee", entries[1].Data["tenant"])
+	assert.Equal(t, http.StatusOK, entries[1].Data[FieldStatus])
+}
+
+func TestLogIngressMessageLogSink(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+
+	var entries []map[string]interface{}
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{
+		LogOnRequestStart: true,
+		LogSink: func(ctx context.Context, fields map[string]interface{}) {
+			assert.True(t, len(GetContextID(ctx)) > 0)
+			entries = append(entries, fields)
+		},
+	})
+
+	logIngressMiddleware.Enforce(http.HandlerFunc(echoHandler)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+
 	assert.Nil(t, hook.LastEntry())
+	if assert.Equal(t, 2, len(entries)) {
+		assert.Equal(t, valueLogTypeIngressStart, entries[0][FieldType])
+		assert.Equal(t, valueLogTypeIngress, entries[1][FieldType])
+		assert.Equal(t, "hello", entries[1][FieldReqBody])
+		assert.Equal(t, http.StatusOK, entries[1][FieldStatus])
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger)
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("database is down")
+	})
+
+	recorder := httptest.NewRecorder()
+	logIngressMiddleware.Recover(panicking).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "panic: database is down.", recorder.Body.String())
+	assert.Nil(t, hook.LastEntry())
+
+	logIngressMiddleware.SetConfig(&Config{
+		PanicResponse: func(recovered interface{}) (int, string, []byte) {
+			return http.StatusServiceUnavailable, "text/plain", []byte("try again later")
+		},
+	})
+
+	recorder = httptest.NewRecorder()
+	logIngressMiddleware.Recover(panicking).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "try again later", recorder.Body.String())
+	assert.Nil(t, hook.LastEntry())
+}
+
+func TestLogMessageResponsePanicStack(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("database is down")
+	})
+
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{LogPanicStack: true})
+	logIngressMiddleware.Enforce(panicking).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, hook.LastEntry().Data[FieldStatus])
+	assert.Contains(t, hook.LastEntry().Data[FieldPanicStack], "TestLogMessageResponsePanicStack")
+
+	logIngressMiddleware = NewIngressLogMiddleware(logger)
+	logIngressMiddleware.Enforce(panicking).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	_, ok := hook.LastEntry().Data[FieldPanicStack]
+	assert.False(t, ok)
+}
+
+func TestLogMessageResponseRePanicAfterLog(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{RePanicAfterLog: true})
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("unexpected")
+	})
+
+	var recovered interface{}
+	outerRecovery := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered = recover()
+				w.WriteHeader(http.StatusBadGateway)
+			}()
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	outerRecovery(logIngressMiddleware.Enforce(panicking)).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, "unexpected", recovered)
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Equal(t, http.StatusInternalServerError, hook.LastEntry().Data[FieldStatus])
+}
+
+func TestLogIngressMessageUserAgentAndReferer(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{
+		ExcludeOpt:   &ExcludeOption{RequestHeader: true},
+		LogUserAgent: true,
+		LogReferer:   true,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/echo", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	req.Header.Set("Referer", "https://shopee.co.id/")
+	logIngressMiddleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "Mozilla/5.0", hook.LastEntry().Data[FieldUserAgent])
+	assert.Equal(t, "https://shopee.co.id/", hook.LastEntry().Data[FieldReferer])
+	_, ok := hook.LastEntry().Data[FieldReqHeader]
+	assert.False(t, ok)
+}
+
+func TestLogIngressMessageLogStatusPredicate(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{
+		LogStatusPredicate: func(status int) bool {
+			return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+		},
+	})
+	defer mockServer.Close()
+
+	client := &http.Client{}
+	for _, code := range []int{http.StatusOK, http.StatusNotModified, http.StatusTooManyRequests, http.StatusBadGateway} {
+		_, err := client.Get(mockServer.URL + "/status?code=" + strconv.Itoa(code))
+		assert.Nil(t, err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	entries := hook.AllEntries()
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, http.StatusTooManyRequests, entries[0].Data[FieldStatus])
+	assert.Equal(t, http.StatusBadGateway, entries[1].Data[FieldStatus])
+}
+
+func TestLogIngressMessageMethodAndPath(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{DisableCombinedURL: true})
+
+	req := httptest.NewRequest(http.MethodPut, "/users/1?force=true", nil)
+	logIngressMiddleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, http.MethodPut, hook.LastEntry().Data[FieldMethod])
+	assert.Equal(t, "/users/1", hook.LastEntry().Data[FieldPath])
+	_, ok := hook.LastEntry().Data[FieldURL]
+	assert.False(t, ok)
+}
+
+func TestLogIngressMessageExcludeSuccessRequestBody(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{
+		ExcludeOpt: &ExcludeOption{SuccessRequestBody: true},
+	})
+	defer mockServer.Close()
+
+	reqBody := `{"name":"shopee"}`
+	client := &http.Client{}
+
+	_, err := client.Post(mockServer.URL+"/status?code=201", "application/json", bytes.NewReader([]byte(reqBody)))
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldReqBody])
+
+	_, err = client.Post(mockServer.URL+"/status?code=422", "application/json", bytes.NewReader([]byte(reqBody)))
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, reqBody, hook.LastEntry().Data[FieldReqBody])
+}
+
+var benchmarkLargeResponseBody = bytes.Repeat([]byte("a"), 1<<20)
+
+func benchmarkEnforceLargeResponse(b *testing.B, excludeOpt *ExcludeOption) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{ExcludeOpt: excludeOpt, MaxBodyBytes: 64})
+	handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(benchmarkLargeResponseBody)
+	}))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/large", nil))
+		hook.Reset()
+	}
+}
+
+func BenchmarkEnforceLargeResponseCaptured(b *testing.B) {
+	benchmarkEnforceLargeResponse(b, &ExcludeOption{})
+}
+
+func BenchmarkEnforceLargeResponseStatusOnly(b *testing.B) {
+	benchmarkEnforceLargeResponse(b, &ExcludeOption{ResponseBody: true})
+}
+
+func TestLogIngressStreamingResponse(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{
+		StreamingPaths: []string{"/stream/*"},
+	})
+	handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events" {
+			w.Header().Set("Content-Type", "text/event-stream")
+		}
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("data: world\n\n"))
+	}))
+
+	for _, path := range []string{"/events", "/stream/chunks"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.True(t, recorder.Flushed, path)
+		assert.Equal(t, "data: hello\n\ndata: world\n\n", recorder.Body.String(), path)
+		assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldResponseBody], path)
+		assert.Equal(t, 26, hook.LastEntry().Data[FieldResponseSize], path)
+	}
+}
+
+func TestLogIngressAfterLog(t *testing.T) {
+	var messages []*LogMessage
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{
+		SuccessSampleRate: 0.000001,
+		AfterLog: func(ctx context.Context, msg *LogMessage) {
+			assert.NotEmpty(t, GetContextID(ctx))
+			messages = append(messages, msg)
+		},
+	})
+	defer mockServer.Close()
+
+	_, err := http.Get(mockServer.URL + "/status?code=200")
+	assert.Nil(t, err)
+	_, err = http.Post(mockServer.URL+"/status?code=500", "application/json", strings.NewReader(`{"name":"shopee"}`))
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, len(hook.AllEntries()))
+	if assert.Equal(t, 2, len(messages)) {
+		assert.Equal(t, http.StatusOK, messages[0].ResponseCode)
+		assert.Equal(t, http.MethodGet, messages[0].ReqMethod)
+		assert.Equal(t, http.StatusInternalServerError, messages[1].ResponseCode)
+		assert.Equal(t, `{"name":"shopee"}`, messages[1].ReqBody)
+		assert.Equal(t, `{"code":500}`, messages[1].ResponseBody)
+	}
+}
+
+func TestLogIngressImplicitStatus(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger)
+
+	for _, handler := range []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("Hello World")) },
+		func(w http.ResponseWriter, r *http.Request) {},
+	} {
+		recorder := httptest.NewRecorder()
+		middleware.Enforce(handler).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, http.StatusOK, hook.LastEntry().Data[FieldStatus])
+	}
+}
+
+func TestLogIngressMaxConcurrentBodyCapture(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{MaxConcurrentBodyCapture: 1})
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	blocking := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-unblock
+		w.Write([]byte("blocking"))
+	}))
+	handler := middleware.Enforce(http.HandlerFunc(echoHandler))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		blocking.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/blocking", strings.NewReader("first")))
+	}()
+	<-started
+
+	// the only capture slot is taken by the blocking request
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("second")))
+	assert.Equal(t, "second", recorder.Body.String())
+	assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldReqBody])
+	assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldResponseBody])
+	assert.Equal(t, 6, hook.LastEntry().Data[FieldReqSize])
+
+	close(unblock)
+	<-done
+	assert.Equal(t, "first", hook.LastEntry().Data[FieldReqBody])
+	assert.Equal(t, "blocking", hook.LastEntry().Data[FieldResponseBody])
+
+	// the slot is released once the request is logged
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("third")))
+	assert.Equal(t, "third", hook.LastEntry().Data[FieldReqBody])
+}
+
+func TestLogIngressMaxConcurrentBodyCaptureRouteWithoutBody(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{
+		MaxConcurrentBodyCapture: 1,
+		RouteConfig: map[string]*Config{
+			"/upload": {ExcludeOpt: &ExcludeOption{RequestBody: ExcludeLog, ResponseBody: ExcludeLog}},
+		},
+	})
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	blocking := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-unblock
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		blocking.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("first")))
+	}()
+	<-started
+
+	// the route logging no body does not hold the only capture slot
+	middleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("second")))
+	assert.Equal(t, "second", hook.LastEntry().Data[FieldReqBody])
+	assert.Equal(t, "second", hook.LastEntry().Data[FieldResponseBody])
+
+	close(unblock)
+	<-done
+}
+
+func TestLogIngressNilResponseWriter(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger)
+
+	request := &LogRequest{
+		URL:    "/hello?page=1",
+		Path:   "/hello",
+		Method: http.MethodGet,
+		Header: make(http.Header),
+	}
+	assert.NotPanics(t, func() {
+		middleware.log(context.Background(), middleware.getConfig(), request, 15*time.Millisecond, time.Now(), nil)
+	})
+
+	entry := hook.LastEntry()
+	assert.Equal(t, "GET /hello?page=1", entry.Data[FieldURL])
+	assert.Equal(t, int64(15), entry.Data[FieldDurationMs])
+	assert.Equal(t, 0, entry.Data[FieldStatus])
+
+	var writer *responseWriter
+	assert.NotPanics(t, writer.release)
+}
+
+func TestGetRequestBodyCaptureLimit(t *testing.T) {
+	body := strings.Repeat("a", 20)
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body))
+
+	loggedBody, rawBody, bodySize, _ := NewConfig(&Config{}).getRequestBody(req, 8)
+	assert.Equal(t, "aaaaaaaa"+truncatedMessage, loggedBody)
+	assert.Nil(t, rawBody)
+	assert.Equal(t, 20, bodySize)
+
+	// the handler still reads the complete body
+	handlerBody, err := ioutil.ReadAll(req.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, body, string(handlerBody))
+
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body))
+	loggedBody, rawBody, bodySize, _ = NewConfig(&Config{}).getRequestBody(req, 20)
+	assert.Equal(t, body, loggedBody)
+	assert.Equal(t, body, string(rawBody))
+	assert.Equal(t, 20, bodySize)
+}
+
+func TestConfigGetMaxBodyCaptureBytes(t *testing.T) {
+	assert.Equal(t, defaultMaxBodyCaptureBytes, NewConfig(&Config{}).getMaxBodyCaptureBytes())
+	assert.Equal(t, 512, NewConfig(&Config{MaxBodyBytes: 512}).getMaxBodyCaptureBytes())
+	assert.Equal(t, 4<<20, NewConfig(&Config{MaxBodyBytes: 4 << 20}).getMaxBodyCaptureBytes())
+}
+
+func TestGetBodyBytesMaxLimit(t *testing.T) {
+	body := ioutil.NopCloser(strings.NewReader("hello"))
+
+	bodyBytes, complete, err := getBodyBytes(&body, math.MaxInt64)
+	assert.Nil(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, "hello", string(bodyBytes))
+}
+
+func TestLogIngressRequestBodyReadError(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger)
+	handler := middleware.Enforce(http.HandlerFunc(echoHandler))
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", iotest.ErrReader(io.ErrUnexpectedEOF))
+	req.ContentLength = 100
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, io.ErrUnexpectedEOF.Error(), hook.LastEntry().Data[FieldReqBodyReadError])
+	_, ok := hook.LastEntry().Data[FieldContentLengthMismatch]
+	assert.False(t, ok)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/upload", nil))
+	_, ok = hook.LastEntry().Data[FieldReqBodyReadError]
+	assert.False(t, ok)
+}
+
+type slowReader struct {
+	io.Reader
+	delay time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	return r.Reader.Read(p)
+}
+
+func TestLogIngressBodyReadMs(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	handler := NewIngressLogMiddleware(logger).Enforce(http.HandlerFunc(echoHandler))
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &slowReader{Reader: strings.NewReader("hello"), delay: 20 * time.Millisecond})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.True(t, hook.LastEntry().Data[FieldBodyReadMs].(int64) >= 20)
+
+	handler = NewIngressLogMiddleware(logger, &Config{ExcludeOpt: &ExcludeOption{RequestBody: true}}).Enforce(http.HandlerFunc(echoHandler))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("hello")))
+	_, ok := hook.LastEntry().Data[FieldBodyReadMs]
+	assert.False(t, ok)
+}
+
+func TestLogIngressNoBodyLogPath(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{NoBodyLogPathPatterns: []string{"^/internal/debug/"}})
+	handler := middleware.Enforce(http.HandlerFunc(echoHandler))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/internal/debug/dump", strings.NewReader("verbose")))
+
+	assert.Equal(t, "verbose", recorder.Body.String())
+	assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldReqBody])
+	assert.Equal(t, wipedMessage, hook.LastEntry().Data[FieldResponseBody])
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("verbose")))
+	assert.Equal(t, "verbose", hook.LastEntry().Data[FieldReqBody])
+	assert.Equal(t, "verbose", hook.LastEntry().Data[FieldResponseBody])
+}
+
+func TestLogIngressClientDisconnected(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the client gives up while the handler is running
+		cancel()
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil).WithContext(ctx))
+	assert.Equal(t, true, hook.LastEntry().Data[FieldClientDisconnected])
+
+	handler = middleware.Enforce(http.HandlerFunc(echoHandler))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/echo", nil))
+	_, ok := hook.LastEntry().Data[FieldClientDisconnected]
+	assert.False(t, ok)
+}
+
+func TestLogIngressAuditLogger(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	auditLogger, auditHook := log.NewLoggerWithTestHook("log-ingress-audit")
+	middleware := NewIngressLogMiddleware(logger, &Config{
+		ExcludeOpt:  &ExcludeOption{SuccessRequest: true},
+		AuditLogger: auditLogger,
+		AuditPredicate: func(msg *LogMessage) bool {
+			return strings.HasPrefix(msg.Path, "/admin/")
+		},
+	})
+	handler := middleware.Enforce(http.HandlerFunc(echoHandler))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admin/users", strings.NewReader(`{"role":"admin"}`)))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"role":"user"}`)))
+
+	// the successful requests are not logged to the main logger
+	assert.Equal(t, 0, len(hook.AllEntries()))
+	if assert.Equal(t, 1, len(auditHook.AllEntries())) {
+		assert.Equal(t, "/admin/users", auditHook.LastEntry().Data[FieldPath])
+		assert.Equal(t, `{"role":"admin"}`, auditHook.LastEntry().Data[FieldReqBody])
+	}
+}
+
+func TestIngressLogSetConfig(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger, &Config{ExcludeOpt: &ExcludeOption{RequestBody: true}})
+	handler := middleware.Enforce(http.HandlerFunc(echoHandler))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+	_, ok := hook.LastEntry().Data[FieldReqBody]
+	assert.False(t, ok)
+
+	assert.Nil(t, middleware.SetConfig(&Config{}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+	assert.Equal(t, "hello", hook.LastEntry().Data[FieldReqBody])
+
+	// nil config is ignored
+	assert.Nil(t, middleware.SetConfig(nil))
+	assert.NotNil(t, middleware.getConfig())
+
+	// the invalid pattern is rejected, the current config is kept
+	assert.NotNil(t, middleware.SetConfig(&Config{RedactPatterns: []string{"("}}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+	assert.Equal(t, "hello", hook.LastEntry().Data[FieldReqBody])
+
+	// the given config is copied, modifying it afterwards doesn't affect the middleware
+	config := &Config{RouteConfig: map[string]*Config{"/admin": {}}}
+	assert.Nil(t, middleware.SetConfig(config))
+	assert.Nil(t, config.ExcludeOpt)
+	assert.Nil(t, config.RouteConfig["/admin"].ExcludeOpt)
+	config.ExcludeOpt = &ExcludeOption{RequestBody: true}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+	assert.Equal(t, "hello", hook.LastEntry().Data[FieldReqBody])
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < 50; n++ {
+			middleware.SetConfig(&Config{DisableIngressLog: n%2 == 0})
+		}
+	}()
+	for n := 0; n < 50; n++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/echo", nil))
+	}
+	<-done
+}
+
+func TestBuildLogRequestContentLengthMismatch(t *testing.T) {
+	middleware := NewIngressLogMiddleware(log.NewLogger("log-ingress-middleware"))
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("hello"))
+	assert.False(t, middleware.buildLogRequest(middleware.getConfig(), req, true).ContentLengthMismatch)
+
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("hello"))
+	req.ContentLength = 10
+	assert.True(t, middleware.buildLogRequest(middleware.getConfig(), req, true).ContentLengthMismatch)
+
+	// unknown declared size, e.g: chunked transfer encoding
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("hello"))
+	req.ContentLength = -1
+	assert.False(t, middleware.buildLogRequest(middleware.getConfig(), req, true).ContentLengthMismatch)
 }
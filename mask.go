@@ -0,0 +1,81 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"strings"
+)
+
+// maskBody is to mask the configured fields of a JSON body, other bodies are returned unchanged
+func (c *Config) maskBody(contentType string, body string) string {
+	if !c.isMaskedBody(contentType) {
+		return body
+	}
+
+	if strings.HasSuffix(body, truncatedMessage) {
+		// the body is only partially captured, it can not be parsed to be masked
+		return wipedMessage
+	}
+
+	return maskJSONBody(body, c.ExcludeOpt.MaskBodyFields, c.GetMaskValue())
+}
+
+// isMaskedBody is to check whether the body of the content type has its MaskBodyFields masked
+func (c *Config) isMaskedBody(contentType string) bool {
+	return c.ExcludeOpt != nil && len(c.ExcludeOpt.MaskBodyFields) > 0 && isJSONContentType(contentType)
+}
+
+// maskJSONBody is to replace the value of the given fields with mask, fields are matched case-insensitively.
+// When the body is not a valid JSON, it is returned unchanged
+func maskJSONBody(body string, fields []string, mask string) string {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return body
+	}
+
+	maskedFields := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		maskedFields[strings.ToLower(field)] = struct{}{}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(maskValue(data, maskedFields, mask)); err != nil {
+		return body
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func maskValue(data interface{}, fields map[string]struct{}, mask string) interface{} {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			if _, ok := fields[strings.ToLower(key)]; ok {
+				value[key] = mask
+				continue
+			}
+			value[key] = maskValue(nested, fields, mask)
+		}
+	case []interface{}:
+		for idx, nested := range value {
+			value[idx] = maskValue(nested, fields, mask)
+		}
+	}
+
+	return data
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,80 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestMaskJSONBody(t *testing.T) {
+	fields := []string{"password", "card_number"}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "top level field",
+			body:     `{"username":"john","password":"secret"}`,
+			expected: `{"password":"-","username":"john"}`,
+		},
+		{
+			name:     "nested field",
+			body:     `{"payment":{"Card_Number":"4111111111111111","amount":10000}}`,
+			expected: `{"payment":{"Card_Number":"-","amount":10000}}`,
+		},
+		{
+			name:     "field inside array",
+			body:     `[{"password":"a"},{"password":"b"}]`,
+			expected: `[{"password":"-"},{"password":"-"}]`,
+		},
+		{
+			name:     "invalid json",
+			body:     `password=secret`,
+			expected: `password=secret`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, maskJSONBody(tt.body, fields, wipedMessage))
+		})
+	}
+}
+
+func TestConfigMaskBodyPartiallyCaptured(t *testing.T) {
+	config := NewConfig(&Config{ExcludeOpt: &ExcludeOption{MaskBodyFields: []string{"password"}}})
+
+	assert.Equal(t, wipedMessage, config.maskBody("application/json", `{"password":"sec`+truncatedMessage))
+	assert.Equal(t, `{"password":"-"}`, config.maskBody("application/json", `{"password":"secret"}`))
+}
+
+func TestLogIngressMessageMaskBodyFields(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{
+		ExcludeOpt: &ExcludeOption{
+			MaskBodyFields: []string{"token"},
+			MaskValue:      "***",
+		},
+	})
+	defer mockServer.Close()
+
+	reqBody := `{"name":"shopee","auth":{"token":"abcdefghijkl"}}`
+	req, _ := http.NewRequest(http.MethodPost, mockServer.URL+"/echo", bytes.NewReader([]byte(reqBody)))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	client := &http.Client{}
+	_, err := client.Do(req)
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	logMessage := extractLogMessage(t, hook.LastEntry().Data)
+	assert.Equal(t, `{"auth":{"token":"***"},"name":"shopee"}`, logMessage.ReqBody)
+	assert.Equal(t, `{"auth":{"token":"***"},"name":"shopee"}`, logMessage.ResponseBody)
+}
@@ -0,0 +1,357 @@
+package httpmiddleware
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TimestampFormat controls how the request timestamp is logged
+type TimestampFormat int
+
+const (
+	TimestampFormatUnix        TimestampFormat = iota // integer seconds
+	TimestampFormatUnixMillis                         // integer milliseconds
+	TimestampFormatRFC3339Nano                        // RFC 3339 string with nanoseconds, e.g: "2006-01-02T15:04:05.999999999Z07:00"
+)
+
+// DurationUnit controls the unit of the logged request duration, the field name is suffixed by the unit
+type DurationUnit int
+
+const (
+	DurationUnitMillis DurationUnit = iota // logged as FieldDurationMs
+	DurationUnitMicros                     // logged as FieldDurationUs
+	DurationUnitNanos                      // logged as FieldDurationNs
+)
+
+// BuildLogMessage is to build the ingress log message of the handled request based on the config,
+// e.g: to emit a custom log with the same fields when DisableIngressLog is true
+func (c *Config) BuildLogMessage(ctx context.Context, request *LogRequest, response *LogResponse, elapsedTime time.Duration, requestTimestamp time.Time) *LogMessage {
+	msg := &LogMessage{
+		ReqMethod:       request.Method,
+		ResponseCode:    response.Status,
+		TimeTakenInMS:   elapsedTime.Milliseconds(),
+		Duration:        elapsedTime,
+		Path:            c.sanitizeURL(request.Path),
+		Route:           request.Route,
+		Proto:           request.Proto,
+		ReqTimestamp:    requestTimestamp,
+		ReqSize:         request.BodySize,
+		ResponseSize:    response.BodySize,
+		Fields:          make(map[string]interface{}),
+		logURL:          !c.DisableCombinedURL,
+		timestampFormat: c.TimestampFormat,
+		durationUnit:    c.DurationUnit,
+		flattenHeaders:  c.FlattenHeaders,
+	}
+
+	msg.URL = c.sanitizeURL(request.URL)
+	if c.LogQueryParams {
+		msg.URL = msg.Path
+		msg.Fields[FieldQueryParams] = c.formatQueryParams(request.Query)
+	}
+
+	if c.LogRouteParams && len(request.RouteParams) > 0 {
+		msg.Fields[FieldRouteParams] = c.formatRouteParams(request.RouteParams)
+	}
+
+	if c.EventNameFunc != nil {
+		if name := c.EventNameFunc(request); len(name) > 0 {
+			msg.Fields[FieldEvent] = c.GetEventPrefix() + name
+		}
+	}
+
+	if c.LogBodySizeRatio && request.BodySize > 0 && response.BodySize > 0 {
+		msg.Fields[FieldBodySizeRatio] = float64(response.BodySize) / float64(request.BodySize)
+	}
+
+	if c.LogRequestFingerprint {
+		if fingerprint, ok := c.requestFingerprint(request); ok {
+			msg.Fields[FieldRequestFingerprint] = fingerprint
+		}
+	}
+
+	if request.bodyRead {
+		msg.Fields[FieldBodyReadMs] = request.BodyReadDuration.Milliseconds()
+	}
+
+	if request.BodyReadError != nil {
+		msg.Fields[FieldReqBodyReadError] = request.BodyReadError.Error()
+	}
+
+	if request.ContentLengthMismatch {
+		msg.Fields[FieldContentLengthMismatch] = true
+	}
+
+	if response.TimedOut {
+		msg.Fields[FieldTimedOut] = true
+	}
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// the client closed the connection before the response is complete
+		msg.Fields[FieldClientDisconnected] = true
+	}
+
+	if response.DoubleWriteHeader {
+		msg.Fields[FieldDoubleWriteHeader] = true
+	}
+
+	if len(response.PanicStack) > 0 {
+		msg.Fields[FieldPanicStack] = response.PanicStack
+	}
+
+	if c.LogTraceContext {
+		appendTraceContext(ctx, msg.Fields)
+	}
+
+	if c.LogTraceParent {
+		appendTraceParent(request.Header.Get(headerNameTraceParent), msg.Fields)
+	}
+
+	if c.LogClientIP() {
+		msg.Fields[FieldClientIP] = c.getClientIP(request)
+	}
+
+	if c.LogHostScheme {
+		msg.Fields[FieldHost] = request.Host
+		msg.Fields[FieldScheme] = getScheme(request)
+	}
+
+	if c.LogUserAgent {
+		msg.Fields[FieldUserAgent] = request.Header.Get(headerNameUserAgent)
+	}
+
+	if c.LogReferer {
+		msg.Fields[FieldReferer] = request.Header.Get(headerNameReferer)
+	}
+
+	if c.LogTLSInfo && request.TLS != nil {
+		msg.Fields[FieldTLSVersion] = tlsVersionName(request.TLS.Version)
+		msg.Fields[FieldTLSCipher] = tls.CipherSuiteName(request.TLS.CipherSuite)
+	}
+
+	if c.LogRequestHeader() {
+		msg.ReqHeader = c.formatRequestHeader(request.Header)
+	}
+
+	// the bodies of the request out of BodySampleRate are wiped, the metadata is always logged
+	noBodyLog := request.bodyCaptureSkipped || c.isBodyWiped(request.Path, GetContextID(ctx), response.Status)
+	if c.LogRequestBody() {
+		msg.logReqBody = true
+		if noBodyLog {
+			msg.ReqBody = wipedMessage
+		} else if c.LogSuccessRequestBody() || !isSuccessStatus(response.Status) {
+			contentType := request.Header.Get(headerNameContentType)
+			if c.isOversizedBody(request.BodySize) {
+				msg.ReqBody = c.formatBodyDigest(contentType, request.bodyDigest)
+			} else if body, ok := c.formatNonTextBody(contentType, request); ok {
+				msg.ReqBody = body
+			} else if body, ok := c.formatBinaryBody(contentType, request.Body); ok {
+				msg.ReqBody = body
+				msg.Fields[FieldReqBodyEncoding] = valueEncodingBase64
+			} else if body, ok := c.formatHeadTailBody(contentType, request.Body, request.bodyTail); ok {
+				msg.ReqBody = body
+			} else {
+				msg.ReqBody = c.formatBody(contentType, request.Body)
+			}
+		} else {
+			msg.ReqBody = wipedMessage
+		}
+	}
+
+	if c.LogResponseHeader() {
+		msg.ResponseHeader = c.formatResponseHeader(response.Header)
+	}
+
+	if c.LogResponseTrailers {
+		if trailer := responseTrailer(response.Header); len(trailer) > 0 {
+			msg.Fields[FieldResponseTrailer] = trailer
+		}
+		if trailer := requestTrailer(request.Trailer); len(trailer) > 0 {
+			msg.Fields[FieldReqTrailer] = trailer
+		}
+	}
+
+	if c.LogResponseBody() {
+		msg.logResponseBody = true
+		msg.ResponseBody = c.loggedResponseBody(noBodyLog, response)
+	}
+
+	if err := getContextError(ctx, c.ErrorContextKey); err != nil {
+		msg.Error = err.Error()
+	}
+
+	mergeFields(msg.Fields, response.Fields)
+
+	if c.ContextFields != nil {
+		mergeFields(msg.Fields, c.ContextFields(ctx))
+	}
+
+	msg.Level = c.getLogLevel(response.Status)
+	if c.IsSlowRequest(msg.TimeTakenInMS) {
+		msg.Fields[FieldSlow] = true
+		if c.SlowRequestWarn && msg.Level > logrus.WarnLevel {
+			msg.Level = logrus.WarnLevel
+		}
+	}
+
+	return msg
+}
+
+// isBodyWiped is to check whether the request/response bodies of the request are logged as "-" regardless of
+// the body flags, i.e: the path matches NoBodyLogPathPatterns or the request is out of BodySampleRate
+func (c *Config) isBodyWiped(path, contextID string, status int) bool {
+	return c.IsNoBodyLogPath(path) || !c.isBodySampled(contextID, status)
+}
+
+// loggedResponseBody is to get the logged response body, "-" when the body is wiped or excluded for the status
+func (c *Config) loggedResponseBody(noBodyLog bool, response *LogResponse) string {
+	if noBodyLog || response.Streaming || !c.isResponseBodyStatusLogged(response.Status) {
+		return wipedMessage
+	}
+
+	return c.formatResponseBody(response)
+}
+
+// formatResponseBody is to prepare the captured response body to be logged, the client still receives the encoded body
+func (c *Config) formatResponseBody(response *LogResponse) string {
+	body := response.Body
+	if response.BodyTruncated {
+		// the partially captured body can not be decoded, its size is the written size
+		if c.isOversizedBody(response.BodySize) {
+			return c.formatBodyDigest(response.Header.Get(headerNameContentType), response.bodyDigest)
+		}
+		if c.MaxLoggedResponseBodyBytes > 0 && response.BodySize > c.MaxLoggedResponseBodyBytes {
+			return fmt.Sprintf(bodyTooLargeMessage, response.BodySize)
+		}
+		if body, ok := c.formatHeadTailBody(response.Header.Get(headerNameContentType), body, response.bodyTail); ok {
+			return body
+		}
+	} else if c.isOversizedBody(len(body)) {
+		// the whole body is captured, e.g: the body given to EffectiveResponseBody
+		return c.formatBodyDigest(response.Header.Get(headerNameContentType), hashBody(body))
+	} else if c.DecodeCompressedBody {
+		decoded, truncated := decodeBody(response.Header.Get(headerNameContentEncoding), []byte(body), c.getMaxBodyCaptureBytes())
+		body = string(decoded)
+		if truncated {
+			body += truncatedMessage
+		}
+	}
+
+	if c.MaxLoggedResponseBodyBytes > 0 && len(body) > c.MaxLoggedResponseBodyBytes {
+		return fmt.Sprintf(bodyTooLargeMessage, len(body))
+	}
+
+	return c.formatBody(response.Header.Get(headerNameContentType), body)
+}
+
+// dataMap is to convert the log message into the logged fields, the built-in fields are not overridden by Fields
+func (m *LogMessage) dataMap() map[string]interface{} {
+	dataMap := make(map[string]interface{}, len(m.Fields)+16)
+	dataMap[FieldType] = valueLogTypeIngress
+	dataMap[FieldMethod] = m.ReqMethod
+	dataMap[FieldPath] = m.Path
+	dataMap[FieldProto] = m.Proto
+	dataMap[FieldReqTimestamp] = formatTimestamp(m.ReqTimestamp, m.timestampFormat)
+	dataMap[FieldStatus] = m.ResponseCode
+	switch m.durationUnit {
+	case DurationUnitMicros:
+		dataMap[FieldDurationUs] = m.Duration.Microseconds()
+	case DurationUnitNanos:
+		dataMap[FieldDurationNs] = m.Duration.Nanoseconds()
+	default:
+		dataMap[FieldDurationMs] = m.TimeTakenInMS
+	}
+	dataMap[FieldReqSize] = m.ReqSize
+	dataMap[FieldResponseSize] = m.ResponseSize
+
+	if m.logURL {
+		dataMap[FieldURL] = m.ReqMethod + " " + m.URL
+	}
+
+	if len(m.Route) > 0 {
+		dataMap[FieldRoute] = m.Route
+	}
+
+	if m.ReqHeader != nil {
+		dataMap[FieldReqHeader] = headerValue(m.ReqHeader, m.flattenHeaders)
+	}
+
+	if m.logReqBody {
+		dataMap[FieldReqBody] = m.ReqBody
+	}
+
+	if m.ResponseHeader != nil {
+		dataMap[FieldResponseHeader] = headerValue(m.ResponseHeader, m.flattenHeaders)
+	}
+
+	if m.logResponseBody {
+		dataMap[FieldResponseBody] = m.ResponseBody
+	}
+
+	if len(m.Error) > 0 {
+		dataMap[FieldError] = m.Error
+	}
+
+	mergeFields(dataMap, m.Fields)
+
+	return dataMap
+}
+
+// shouldLog is to check whether the ingress log of the response status is emitted
+func (c *Config) shouldLog(contextID string, status int) bool {
+	return !c.DisableIngressLog && c.isLoggedRequest(contextID, status)
+}
+
+// isLoggedRequest is to check whether the request passes the status rules and the success sampling of the ingress log
+func (c *Config) isLoggedRequest(contextID string, status int) bool {
+	if c.LogFailedRequestOnly() && isSuccessStatus(status) {
+		return false
+	}
+	if c.LogStatusPredicate != nil && !c.LogStatusPredicate(status) {
+		return false
+	}
+
+	return c.isSuccessSampled(contextID, status)
+}
+
+// ShouldLog is to check whether the ingress log of the request path would log the request with the response status,
+// e.g: for a custom log emitted when DisableIngressLog is true. The same status rules and sampling by the context id
+// of ctx apply, only DisableIngressLog itself is not
+func (c *Config) ShouldLog(ctx context.Context, path string, status int) bool {
+	return c.GetRouteConfig(path).isLoggedRequest(GetContextID(ctx), status)
+}
+
+// EffectiveResponseBody is to get the response body as it is logged by the ingress log of the request path,
+// e.g: for a custom log emitted when DisableIngressLog is true. The response header is used for the content type
+// and encoding, the context id of ctx for BodySampleRate. It is "-" when the body is excluded and empty when the
+// response body is not logged at all
+func (c *Config) EffectiveResponseBody(ctx context.Context, path string, response *LogResponse) string {
+	conf := c.GetRouteConfig(path)
+	if !conf.LogResponseBody() {
+		return ""
+	}
+
+	return conf.loggedResponseBody(conf.isBodyWiped(path, GetContextID(ctx), response.Status), response)
+}
+
+// isResponseBodyStatusLogged is to check whether the response body of the status is logged
+func (c *Config) isResponseBodyStatusLogged(status int) bool {
+	return c.LogResponseBodyStatus(status) && (c.LogSuccessResponseBody() || !isSuccessStatus(status))
+}
+
+// formatTimestamp is to get the logged value of the timestamp in the given format
+func formatTimestamp(timestamp time.Time, format TimestampFormat) interface{} {
+	switch format {
+	case TimestampFormatUnixMillis:
+		return timestamp.UnixMilli()
+	case TimestampFormatRFC3339Nano:
+		return timestamp.Format(time.RFC3339Nano)
+	default:
+		return timestamp.Unix()
+	}
+}
@@ -0,0 +1,271 @@
+package httpmiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+	"github.com/sirupsen/logrus"
+)
+
+func TestConfigBuildLogMessage(t *testing.T) {
+	config := NewConfig(&Config{
+		ExcludeOpt:             &ExcludeOption{ResponseHeader: true},
+		LogQueryParams:         true,
+		SensitiveQueryKeys:     []string{"token"},
+		StatusBasedLogLevel:    true,
+		SlowRequestThresholdMs: 100,
+		ContextFields: func(ctx context.Context) map[string]interface{} {
+			return map[string]interface{}{"tenant": "shopee", FieldStatus: "overridden"}
+		},
+	})
+
+	request := &LogRequest{
+		URL:    "/users/1?token=secret",
+		Path:   "/users/1",
+		Route:  "/users/:id",
+		Query:  url.Values{"token": []string{"secret"}},
+		Method: http.MethodPost,
+		Proto:  "HTTP/2.0",
+		Header: http.Header{"Authorization": []string{"Bearer secret"}, "Content-Type": []string{"application/json"}},
+		Body:   `{"name":"shopee"}`,
+	}
+	response := &LogResponse{
+		Status:   http.StatusNotFound,
+		Header:   http.Header{"Content-Type": []string{"application/json"}},
+		Body:     `{"error":"not found"}`,
+		BodySize: 21,
+	}
+	requestTimestamp := time.Unix(1700000000, 0)
+
+	msg := config.BuildLogMessage(context.Background(), request, response, 150*time.Millisecond, requestTimestamp)
+	assert.Equal(t, "/users/1", msg.URL)
+	assert.Equal(t, http.Header{"Content-Type": []string{"application/json"}}, msg.ReqHeader)
+	assert.Equal(t, request.Body, msg.ReqBody)
+	assert.Nil(t, msg.ResponseHeader)
+	assert.Equal(t, response.Body, msg.ResponseBody)
+	assert.Equal(t, logrus.WarnLevel, msg.Level)
+
+	dataMap := msg.dataMap()
+	assert.Equal(t, "POST /users/1", dataMap[FieldURL])
+	assert.Equal(t, "/users/:id", dataMap[FieldRoute])
+	assert.Equal(t, "HTTP/2.0", dataMap[FieldProto])
+	assert.Equal(t, int64(1700000000), dataMap[FieldReqTimestamp])
+	assert.Equal(t, http.StatusNotFound, dataMap[FieldStatus])
+	assert.Equal(t, 21, dataMap[FieldResponseSize])
+	assert.Equal(t, url.Values{"token": []string{wipedMessage}}, dataMap[FieldQueryParams])
+	assert.Equal(t, true, dataMap[FieldSlow])
+	assert.Equal(t, "shopee", dataMap["tenant"])
+	_, ok := dataMap[FieldResponseHeader]
+	assert.False(t, ok)
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	timestamp := time.Date(2023, 11, 14, 22, 13, 20, 123456789, time.UTC)
+
+	assert.Equal(t, int64(1700000000), formatTimestamp(timestamp, TimestampFormatUnix))
+	assert.Equal(t, int64(1700000000123), formatTimestamp(timestamp, TimestampFormatUnixMillis))
+	assert.Equal(t, "2023-11-14T22:13:20.123456789Z", formatTimestamp(timestamp, TimestampFormatRFC3339Nano))
+}
+
+func TestConfigBuildLogMessageMaxLoggedResponseBodyBytes(t *testing.T) {
+	config := NewConfig(&Config{MaxLoggedResponseBodyBytes: 10})
+	request := &LogRequest{Method: http.MethodGet, Path: "/report", Header: make(http.Header)}
+
+	msg := config.BuildLogMessage(context.Background(), request, &LogResponse{Status: http.StatusBadRequest, Body: "small"}, 0, time.Now())
+	assert.Equal(t, "small", msg.ResponseBody)
+
+	msg = config.BuildLogMessage(context.Background(), request, &LogResponse{Status: http.StatusBadRequest, Body: "a very large report"}, 0, time.Now())
+	assert.Equal(t, "body too large (19 bytes)", msg.ResponseBody)
+}
+
+func TestLogMessageDurationUnit(t *testing.T) {
+	request := &LogRequest{Method: http.MethodGet, Path: "/hello", Header: make(http.Header)}
+	elapsedTime := 1234567 * time.Nanosecond
+
+	tests := []struct {
+		unit     DurationUnit
+		field    string
+		expected int64
+	}{
+		{unit: DurationUnitMillis, field: FieldDurationMs, expected: 1},
+		{unit: DurationUnitMicros, field: FieldDurationUs, expected: 1234},
+		{unit: DurationUnitNanos, field: FieldDurationNs, expected: 1234567},
+	}
+
+	for _, tt := range tests {
+		config := NewConfig(&Config{DurationUnit: tt.unit})
+		dataMap := config.BuildLogMessage(context.Background(), request, &LogResponse{}, elapsedTime, time.Now()).dataMap()
+		assert.Equal(t, tt.expected, dataMap[tt.field], tt.field)
+	}
+}
+
+func TestConfigBuildLogMessageEventName(t *testing.T) {
+	config := NewConfig(&Config{
+		FieldOpt: &FieldOption{EventPrefix: "shop"},
+		EventNameFunc: func(request *LogRequest) string {
+			if request.Route == "/login" {
+				return "user.login"
+			}
+			return ""
+		},
+	})
+	response := &LogResponse{Status: http.StatusOK}
+
+	msg := config.BuildLogMessage(context.Background(), &LogRequest{Path: "/login", Route: "/login"}, response, 0, time.Now())
+	assert.Equal(t, "shop/user.login", msg.dataMap()[FieldEvent])
+
+	msg = config.BuildLogMessage(context.Background(), &LogRequest{Path: "/health"}, response, 0, time.Now())
+	_, ok := msg.dataMap()[FieldEvent]
+	assert.False(t, ok)
+}
+
+func TestConfigBuildLogMessageBase64BinaryBodies(t *testing.T) {
+	config := NewConfig(&Config{Base64BinaryBodies: true})
+	response := &LogResponse{Status: http.StatusOK, Header: http.Header{}}
+
+	request := &LogRequest{Method: http.MethodPost, Header: http.Header{}, Body: "\xff\xfe\x00binary"}
+	dataMap := config.BuildLogMessage(context.Background(), request, response, 0, time.Now()).dataMap()
+	assert.Equal(t, "//4AYmluYXJ5", dataMap[FieldReqBody])
+	assert.Equal(t, "base64", dataMap[FieldReqBodyEncoding])
+
+	request.Body = `{"name":"shopee"}`
+	dataMap = config.BuildLogMessage(context.Background(), request, response, 0, time.Now()).dataMap()
+	assert.Equal(t, `{"name":"shopee"}`, dataMap[FieldReqBody])
+	_, ok := dataMap[FieldReqBodyEncoding]
+	assert.False(t, ok)
+}
+
+func TestConfigBuildLogMessageTextBodiesOnly(t *testing.T) {
+	config := NewConfig(&Config{TextBodiesOnly: true})
+	response := &LogResponse{Status: http.StatusOK, Header: http.Header{}}
+	multipartContentType := "multipart/form-data; boundary=xyz"
+	summary := `{"fields":["name"],"files":["avatar"]}`
+
+	tests := []struct {
+		contentType string
+		body        string
+		bodySize    int
+		expected    string
+	}{
+		{contentType: "application/json; charset=utf-8", body: `{"name":"shopee"}`, bodySize: 17, expected: `{"name":"shopee"}`},
+		{contentType: "text/plain", body: "hello", bodySize: 5, expected: "hello"},
+		{contentType: "application/x-www-form-urlencoded", body: "name=shopee", bodySize: 11, expected: "name=shopee"},
+		{contentType: "image/png", body: "\x89PNG", bodySize: 4, expected: "<binary image/png body, 4 bytes>"},
+		{contentType: "", body: "payload", bodySize: 7, expected: "<binary application/octet-stream body, 7 bytes>"},
+		// the size is the request body size, not the length of the partially captured body
+		{contentType: "image/png", body: "\x89PNG" + truncatedMessage, bodySize: 2048, expected: "<binary image/png body, 2048 bytes>"},
+		{contentType: multipartContentType, body: summary, bodySize: 4096, expected: summary},
+		{contentType: multipartContentType, body: "--xyz" + truncatedMessage, bodySize: 4096, expected: "<binary multipart/form-data body, 4096 bytes>"},
+	}
+
+	for _, tt := range tests {
+		request := &LogRequest{Method: http.MethodPost, Header: http.Header{headerNameContentType: []string{tt.contentType}}, Body: tt.body, BodySize: tt.bodySize}
+		dataMap := config.BuildLogMessage(context.Background(), request, response, 0, time.Now()).dataMap()
+		assert.Equal(t, tt.expected, dataMap[FieldReqBody], tt.contentType)
+	}
+}
+
+func TestConfigBuildLogMessageRequestFingerprint(t *testing.T) {
+	request := &LogRequest{Method: http.MethodPost, Path: "/orders", Header: http.Header{}, Body: `{"item":1}`, rawBody: []byte(`{"item":1}`)}
+	response := &LogResponse{Status: http.StatusOK, Header: http.Header{}}
+
+	dataMap := NewConfig(&Config{LogRequestFingerprint: true}).BuildLogMessage(context.Background(), request, response, 0, time.Now()).dataMap()
+	assert.Equal(t, "53897306b7047406a214b0647a790e08c5d87a7b", dataMap[FieldRequestFingerprint])
+
+	dataMap = NewConfig(&Config{}).BuildLogMessage(context.Background(), request, response, 0, time.Now()).dataMap()
+	_, ok := dataMap[FieldRequestFingerprint]
+	assert.False(t, ok)
+}
+
+func TestConfigBuildLogMessageBodySizeRatio(t *testing.T) {
+	config := NewConfig(&Config{LogBodySizeRatio: true})
+
+	msg := config.BuildLogMessage(context.Background(), &LogRequest{BodySize: 20}, &LogResponse{Status: http.StatusOK, BodySize: 500}, 0, time.Now())
+	assert.Equal(t, 25.0, msg.dataMap()[FieldBodySizeRatio])
+
+	msg = config.BuildLogMessage(context.Background(), &LogRequest{}, &LogResponse{Status: http.StatusOK, BodySize: 500}, 0, time.Now())
+	_, ok := msg.dataMap()[FieldBodySizeRatio]
+	assert.False(t, ok)
+}
+
+func TestConfigBuildLogMessageURLSanitizer(t *testing.T) {
+	config := NewConfig(&Config{
+		URLSanitizer: func(path string) string {
+			segments := strings.Split(path, "/")
+			if len(segments) > 2 && segments[1] == "reset-password" {
+				segments[2] = "*"
+			}
+			return strings.Join(segments, "/")
+		},
+	})
+	request := &LogRequest{
+		Method: http.MethodPost,
+		URL:    "/reset-password/secret-token/confirm?lang=id",
+		Path:   "/reset-password/secret-token/confirm",
+	}
+
+	dataMap := config.BuildLogMessage(context.Background(), request, &LogResponse{Status: http.StatusOK}, 0, time.Now()).dataMap()
+	assert.Equal(t, "POST /reset-password/*/confirm?lang=id", dataMap[FieldURL])
+	assert.Equal(t, "/reset-password/*/confirm", dataMap[FieldPath])
+}
+
+func TestConfigShouldLog(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, NewConfig(&Config{DisableIngressLog: true}).ShouldLog(ctx, "/users", http.StatusOK))
+
+	config := NewConfig(&Config{ExcludeOpt: &ExcludeOption{SuccessRequest: true}})
+	assert.False(t, config.ShouldLog(ctx, "/users", http.StatusOK))
+	assert.False(t, config.ShouldLog(ctx, "/users", http.StatusCreated))
+	assert.True(t, config.ShouldLog(ctx, "/users", http.StatusBadRequest))
+
+	config = NewConfig(&Config{LogStatusPredicate: func(status int) bool { return status != http.StatusNotFound }})
+	assert.False(t, config.ShouldLog(ctx, "/users", http.StatusNotFound))
+	assert.True(t, config.ShouldLog(ctx, "/users", http.StatusCreated))
+
+	// the route config applies to its path
+	config = NewConfig(&Config{RouteConfig: map[string]*Config{"/admin": {ExcludeOpt: &ExcludeOption{SuccessRequest: true}}}})
+	assert.False(t, config.ShouldLog(ctx, "/admin/users", http.StatusOK))
+	assert.True(t, config.ShouldLog(ctx, "/users", http.StatusOK))
+
+	// the same sampling decision as the middleware for the context id
+	config = NewConfig(&Config{SuccessSampleRate: 0.0001})
+	for idx := 0; idx < 100; idx++ {
+		contextID := fmt.Sprintf("context-id-%d", idx)
+		ctx := context.WithValue(context.Background(), log.ContextDataMapKey, map[string]string{log.ContextIdKey: contextID})
+		assert.Equal(t, config.shouldLog(contextID, http.StatusOK), config.ShouldLog(ctx, "/users", http.StatusOK))
+	}
+}
+
+func TestConfigEffectiveResponseBody(t *testing.T) {
+	config := NewConfig(&Config{
+		ExcludeOpt: &ExcludeOption{
+			SuccessResponseBody: true,
+			MaskBodyFields:      []string{"token"},
+		},
+		ExcludeResponseBodyStatuses: []int{http.StatusNotFound},
+		MaxBodyBytes:                40,
+		NoBodyLogPathPatterns:       []string{"^/login$"},
+	})
+	ctx := context.Background()
+	jsonHeader := http.Header{headerNameContentType: []string{"application/json"}}
+
+	assert.Equal(t, wipedMessage, config.EffectiveResponseBody(ctx, "/users", &LogResponse{Status: http.StatusOK, Body: "created"}))
+	assert.Equal(t, wipedMessage, config.EffectiveResponseBody(ctx, "/users", &LogResponse{Status: http.StatusNotFound, Body: "not found"}))
+	assert.Equal(t, "internal error, retry in a minute please"[:40]+truncatedMessage,
+		config.EffectiveResponseBody(ctx, "/users", &LogResponse{Status: http.StatusInternalServerError, Body: "internal error, retry in a minute please!"}))
+
+	// the body is masked by the content type of the response header
+	response := &LogResponse{Status: http.StatusInternalServerError, Header: jsonHeader, Body: `{"token":"secret"}`}
+	assert.Equal(t, `{"token":"-"}`, config.EffectiveResponseBody(ctx, "/users", response))
+	assert.Equal(t, wipedMessage, config.EffectiveResponseBody(ctx, "/login", response))
+
+	config = NewConfig(&Config{ExcludeOpt: &ExcludeOption{ResponseBody: true}})
+	assert.Equal(t, "", config.EffectiveResponseBody(ctx, "/users", &LogResponse{Status: http.StatusInternalServerError, Body: "internal error"}))
+}
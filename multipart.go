@@ -0,0 +1,58 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+)
+
+const mediaTypeMultipartFormData = "multipart/form-data"
+
+// multipartSummary is the logged form of multipart/form-data body, listing the part names only
+type multipartSummary struct {
+	Fields []string `json:"fields"`
+	Files  []string `json:"files"`
+}
+
+// summarizeMultipartBody is to summarize multipart/form-data body into its field and file names,
+// so the file contents are not logged. It returns false when the body is not a valid multipart form
+func summarizeMultipartBody(contentType string, body []byte) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != mediaTypeMultipartFormData || len(params["boundary"]) == 0 {
+		return "", false
+	}
+
+	summary := multipartSummary{Fields: []string{}, Files: []string{}}
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", false
+		}
+
+		if len(part.FileName()) > 0 {
+			summary.Files = append(summary.Files, part.FormName())
+		} else {
+			summary.Fields = append(summary.Fields, part.FormName())
+		}
+	}
+
+	summaryBytes, err := json.Marshal(summary)
+	if err != nil {
+		return "", false
+	}
+
+	return string(summaryBytes), true
+}
+
+// isMultipartFormData is to check whether the body of the content type is a multipart form
+func isMultipartFormData(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == mediaTypeMultipartFormData
+}
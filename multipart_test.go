@@ -0,0 +1,52 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestLogIngressMessageMultipartBody(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("name", "shopee")
+	writer.WriteField("email", "shopee@shopee.com")
+	file, _ := writer.CreateFormFile("avatar", "avatar.png")
+	file.Write([]byte("\x89PNG binary content"))
+	writer.Close()
+
+	var receivedAvatar string
+	upload := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("avatar")
+		assert.Nil(t, err)
+
+		content, _ := ioutil.ReadAll(file)
+		receivedAvatar = string(content)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body.Bytes()))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	logIngressMiddleware.Enforce(upload).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "\x89PNG binary content", receivedAvatar)
+	assert.Equal(t, `{"fields":["name","email"],"files":["avatar"]}`, hook.LastEntry().Data[FieldReqBody])
+	assert.Equal(t, body.Len(), hook.LastEntry().Data[FieldReqSize])
+}
+
+func TestSummarizeMultipartBodyInvalid(t *testing.T) {
+	_, ok := summarizeMultipartBody("application/json", []byte(`{}`))
+	assert.False(t, ok)
+
+	_, ok = summarizeMultipartBody("multipart/form-data; boundary=xyz", []byte("not a multipart body"))
+	assert.False(t, ok)
+}
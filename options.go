@@ -0,0 +1,87 @@
+package httpmiddleware
+
+// Option configures the ingress log middleware. *Config is an Option as well,
+// so the struct-based configuration can still be passed to NewIngressLogMiddleware
+type Option interface {
+	apply(c *Config) *Config
+}
+
+// OptionFunc is an Option mutating the middleware config
+type OptionFunc func(c *Config)
+
+func (f OptionFunc) apply(c *Config) *Config {
+	f(c)
+	return c
+}
+
+// apply is to replace the whole config with a copy of c, nil config keeps the current one. The final config
+// is prepared by NewConfig once every option is applied
+func (c *Config) apply(current *Config) *Config {
+	if c == nil {
+		return current
+	}
+
+	return c.clone()
+}
+
+// WithExcludeOption is to set which parts of the request/response are excluded from the log
+func WithExcludeOption(opt *ExcludeOption) OptionFunc {
+	return func(c *Config) {
+		if opt == nil {
+			opt = &ExcludeOption{}
+		}
+		c.ExcludeOpt = opt
+	}
+}
+
+// WithFieldOption is to set the log field option
+func WithFieldOption(opt *FieldOption) OptionFunc {
+	return func(c *Config) {
+		c.FieldOpt = opt
+	}
+}
+
+// WithDisableIngressLog is to disable the default ingress log
+func WithDisableIngressLog() OptionFunc {
+	return func(c *Config) {
+		c.DisableIngressLog = true
+	}
+}
+
+// WithMaxBodyBytes is to truncate the logged request/response body to n bytes
+func WithMaxBodyBytes(n int) OptionFunc {
+	return func(c *Config) {
+		c.MaxBodyBytes = n
+	}
+}
+
+// WithSensitiveHeaders is to set the header keys stripped from the logged request/response header
+func WithSensitiveHeaders(keys ...string) OptionFunc {
+	return func(c *Config) {
+		c.SensitiveHeaderKeys = append([]string{}, keys...)
+	}
+}
+
+// WithSkipPaths is to set the request paths served without ingress log
+func WithSkipPaths(paths ...string) OptionFunc {
+	return func(c *Config) {
+		c.SkipPaths = append(c.SkipPaths, paths...)
+	}
+}
+
+// WithStatusBasedLogLevel is to log 5xx response as error and 4xx response as warning
+func WithStatusBasedLogLevel() OptionFunc {
+	return func(c *Config) {
+		c.StatusBasedLogLevel = true
+	}
+}
+
+// WithRouteConfig is to override the config used to build the log entry of the matching request path
+func WithRouteConfig(pathPrefix string, routeConfig *Config) OptionFunc {
+	return func(c *Config) {
+		if c.RouteConfig == nil {
+			c.RouteConfig = make(map[string]*Config)
+		}
+		c.RouteConfig[pathPrefix] = NewConfig(routeConfig)
+	}
+}
@@ -0,0 +1,49 @@
+package httpmiddleware
+
+import (
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestNewIngressLogMiddlewareOptions(t *testing.T) {
+	logger := log.NewLogger("log-ingress-middleware")
+
+	middleware := NewIngressLogMiddleware(logger,
+		WithSkipPaths("/healthz", "/metrics/*"),
+		WithMaxBodyBytes(1024),
+		WithSensitiveHeaders("Cookie", "X-Api-Key"),
+	)
+
+	assert.NotNil(t, middleware.getConfig().ExcludeOpt)
+	assert.Equal(t, []string{"/healthz", "/metrics/*"}, middleware.getConfig().SkipPaths)
+	assert.Equal(t, 1024, middleware.getConfig().MaxBodyBytes)
+	assert.Equal(t, []string{"Cookie", "X-Api-Key"}, middleware.getConfig().GetSensitiveHeaderKeys())
+}
+
+func TestNewIngressLogMiddlewareConfig(t *testing.T) {
+	logger := log.NewLogger("log-ingress-middleware")
+
+	var nilConfig *Config
+	middleware := NewIngressLogMiddleware(logger, nilConfig)
+	assert.NotNil(t, middleware.getConfig().ExcludeOpt)
+
+	config := &Config{DisableIngressLog: true}
+	middleware = NewIngressLogMiddleware(logger, config, WithMaxBodyBytes(10))
+	assert.True(t, middleware.getConfig().DisableIngressLog)
+	assert.NotNil(t, middleware.getConfig().ExcludeOpt)
+	assert.Equal(t, 10, middleware.getConfig().MaxBodyBytes)
+}
+
+func TestNewIngressLogMiddlewareOptionsCompilePatterns(t *testing.T) {
+	logger := log.NewLogger("log-ingress-middleware")
+
+	middleware := NewIngressLogMiddleware(logger, OptionFunc(func(c *Config) {
+		c.RedactPatterns = []string{RedactPatternPAN}
+		c.NoBodyLogPathPatterns = []string{"^/login$"}
+	}))
+
+	assert.Equal(t, "card "+wipedMessage, middleware.getConfig().redactBody("card 4111 1111 1111 1111"))
+	assert.True(t, middleware.getConfig().IsNoBodyLogPath("/login"))
+}
@@ -0,0 +1,62 @@
+package httpmiddleware
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Presets of RedactPatterns for the common sensitive values
+const (
+	RedactPatternPAN   = `\b(?:\d[ -]?){12,18}\d\b`                         // payment card number, e.g: "4111 1111 1111 1111"
+	RedactPatternSSN   = `\b\d{3}-\d{2}-\d{4}\b`                            // US social security number, e.g: "123-45-6789"
+	RedactPatternEmail = `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}` // email address, e.g: "john@example.com"
+)
+
+var (
+	cutLeadingDigits  = regexp.MustCompile(`^[ -]*\d[\d -]*`)
+	cutTrailingDigits = regexp.MustCompile(`\d[\d -]*$`)
+)
+
+// compilePatterns is to compile the regexp patterns of the config, e.g: RedactPatterns
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		regexps = append(regexps, compiled)
+	}
+
+	return regexps, nil
+}
+
+// redactBody is to replace the values matching the redact patterns anywhere in the body with wipedMessage
+func (c *Config) redactBody(body string) string {
+	for _, pattern := range c.redactRegexps {
+		body = pattern.ReplaceAllLiteralString(body, wipedMessage)
+	}
+
+	if strings.HasSuffix(body, truncatedMessage) {
+		body = c.redactCutDigits(strings.TrimSuffix(body, truncatedMessage), false, true) + truncatedMessage
+	}
+
+	return body
+}
+
+// redactCutDigits is to wipe the run of digits at the leading and/or trailing edge where the body is cut by the
+// capture limit. A value split there, e.g: a PAN, can't be matched by RedactPatterns but its digits are sensitive
+func (c *Config) redactCutDigits(part string, leading, trailing bool) string {
+	if len(c.redactRegexps) == 0 {
+		return part
+	}
+
+	if leading {
+		part = cutLeadingDigits.ReplaceAllLiteralString(part, wipedMessage)
+	}
+	if trailing {
+		part = cutTrailingDigits.ReplaceAllLiteralString(part, wipedMessage)
+	}
+
+	return part
+}
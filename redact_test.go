@@ -0,0 +1,97 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestRedactBody(t *testing.T) {
+	config := NewConfig(&Config{
+		RedactPatterns: []string{RedactPatternPAN, RedactPatternSSN, RedactPatternEmail},
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "card number in any field",
+			body:     `{"note":"paid with 4111 1111 1111 1111","amount":10000}`,
+			expected: `{"note":"paid with -","amount":10000}`,
+		},
+		{
+			name:     "ssn",
+			body:     `ssn=123-45-6789`,
+			expected: `ssn=-`,
+		},
+		{
+			name:     "email",
+			body:     `{"contact":"john.doe@example.com"}`,
+			expected: `{"contact":"-"}`,
+		},
+		{
+			name:     "no match",
+			body:     `{"amount":10000}`,
+			expected: `{"amount":10000}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, config.formatBody("application/json", tt.body))
+		})
+	}
+}
+
+func TestRedactBodyWithoutPatterns(t *testing.T) {
+	config := NewConfig(&Config{})
+
+	assert.Equal(t, `{"contact":"john@example.com"}`, config.redactBody(`{"contact":"john@example.com"}`))
+}
+
+func TestLogIngressRedactTruncatedBody(t *testing.T) {
+	body := `{"card":"4111 1111 1111 1111","note":"` + strings.Repeat("x", 30) + `","card":"5500 0000 0000 0004"}`
+
+	tests := []struct {
+		name          string
+		maxBodyBytes  int
+		headTailBytes int
+		expected      string
+	}{
+		{
+			name:         "pan cut by the capture limit",
+			maxBodyBytes: 16,
+			expected:     `{"card":"-` + truncatedMessage,
+		},
+		{
+			name:          "pan cut by the head and tail",
+			maxBodyBytes:  16,
+			headTailBytes: 12,
+			expected:      `{"card":"-...[75 bytes omitted]...-"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+			middleware := NewIngressLogMiddleware(logger, &Config{
+				MaxBodyBytes:   tt.maxBodyBytes,
+				HeadTailBytes:  tt.headTailBytes,
+				RedactPatterns: []string{RedactPatternPAN},
+			})
+
+			request := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+			middleware.Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(), request)
+
+			assert.Equal(t, tt.expected, hook.LastEntry().Data[FieldReqBody])
+			assert.Equal(t, tt.expected, hook.LastEntry().Data[FieldResponseBody])
+		})
+	}
+}
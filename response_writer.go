@@ -0,0 +1,241 @@
+package httpmiddleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxPooledBodyBufferSize is the largest buffer kept in the pool, so a huge response does not pin its memory
+const maxPooledBodyBufferSize = 64 << 10
+
+var errHijackNotSupported = errors.New("httpmiddleware: response writer does not implement http.Hijacker")
+
+// bodyBufferPool keeps the response body buffers to be reused across requests
+var bodyBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// responseWriter is a response wrapper capturing the status and body written by the handler
+type responseWriter struct {
+	http.ResponseWriter
+	Status     int
+	TimedOut   bool
+	Streaming  bool   // true: the response is streamed, its body is not captured
+	PanicStack string // the stack trace of the recovered handler panic
+	// DoubleWriteHeader is true when WriteHeader is called after the header is written, i.e: the superfluous WriteHeader call
+	DoubleWriteHeader bool
+
+	body          *bytes.Buffer // nil when the body is not captured
+	captureLimit  int           // the maximum captured body bytes, the client still receives the whole body
+	bodyTruncated bool          // true: the body is longer than captureLimit
+	hashOversized bool          // true: the body longer than captureLimit is hashed into digest
+	digest        *bodyDigest
+	headTailBytes int // the tail size of the body longer than captureLimit kept into tail, 0 means disabled
+	tail          *bodyTail
+	size          int
+	headerChecked bool
+	wroteHeader   bool
+	fields        map[string]interface{} // added by the handler through AddLogField
+}
+
+// logFieldAdder is the response writer accepting the ingress log fields from the handler
+type logFieldAdder interface {
+	AddLogField(key string, val interface{})
+}
+
+// AddLogField is to add the field to the ingress log of the request served with the response writer,
+// e.g: a resolved tenant or a cache-hit flag computed by the handler. The built-in fields are not overridden,
+// the field is ignored when the response writer is not wrapped by the ingress log middleware
+func AddLogField(w http.ResponseWriter, key string, val interface{}) {
+	if adder, ok := w.(logFieldAdder); ok {
+		adder.AddLogField(key, val)
+	}
+}
+
+func newResponseWriter(w http.ResponseWriter, captureLimit int, hashOversized bool, headTailBytes int) *responseWriter {
+	return &responseWriter{
+		ResponseWriter: w,
+		body:           bodyBufferPool.Get().(*bytes.Buffer),
+		captureLimit:   captureLimit,
+		hashOversized:  hashOversized,
+		headTailBytes:  headTailBytes,
+	}
+}
+
+// newStatusWriter is a lightweight response wrapper capturing only the status and body size,
+// used when the response body is not logged
+func newStatusWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w}
+}
+
+// newStreamingWriter is a response wrapper passing the streamed response through without capturing its body
+func newStreamingWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, Streaming: true}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.detectStreaming()
+	if w.wroteHeader {
+		// net/http ignores the superfluous call, the client receives the first status
+		w.DoubleWriteHeader = true
+		return
+	}
+	w.wroteHeader = true
+	w.Status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(body []byte) (int, error) {
+	w.detectStreaming()
+	if !w.wroteHeader {
+		// the first Write implicitly writes the 200 header
+		w.wroteHeader = true
+		w.Status = http.StatusOK
+	}
+	if w.body != nil {
+		w.capture(body)
+	}
+	n, err := w.ResponseWriter.Write(body)
+	w.size += n
+	return n, err
+}
+
+// capture is to buffer the written body up to captureLimit, the rest is only counted by the size, hashed
+// when hashOversized is true and its tail is kept when headTailBytes is set
+func (w *responseWriter) capture(body []byte) {
+	overflow := len(body) > w.captureLimit-w.body.Len()
+	if w.hashOversized && w.digest == nil && overflow {
+		// the digest starts from the captured part, the body is hashed from then on
+		w.digest = newBodyDigest()
+		w.digest.Write(w.body.Bytes())
+	}
+	if w.digest != nil {
+		w.digest.Write(body)
+	}
+	if w.headTailBytes > 0 && w.tail == nil && overflow {
+		w.tail = newBodyTail(w.headTailBytes)
+		w.tail.Write(w.body.Bytes())
+	}
+	if w.tail != nil {
+		w.tail.Write(body)
+	}
+
+	room := w.captureLimit - w.body.Len()
+	if len(body) > room {
+		w.bodyTruncated = true
+		body = body[:room]
+	}
+	w.body.Write(body)
+}
+
+// Flush is to send the buffered data to the client, so the streamed response is delivered as it is written
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack is to let the handler take over the connection, e.g: for the WebSocket upgrade. The handler served
+// with HandlerTimeout cannot hijack the connection
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackNotSupported
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && w.Status == 0 {
+		// the upgrade response is written to the connection directly
+		w.Status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+// AddLogField is to add the field to the ingress log of the request, see AddLogField
+func (w *responseWriter) AddLogField(key string, val interface{}) {
+	if w.fields == nil {
+		w.fields = make(map[string]interface{})
+	}
+	w.fields[key] = val
+}
+
+// detectStreaming is to stop capturing the body once the response turns out to be an event stream,
+// the content type is checked once, when the header is written
+func (w *responseWriter) detectStreaming() {
+	if w.headerChecked {
+		return
+	}
+	w.headerChecked = true
+
+	if strings.HasPrefix(w.Header().Get(headerNameContentType), contentTypeEventStream) {
+		w.Streaming = true
+		w.release()
+	}
+}
+
+// Body is to get a copy of the captured response body, empty when the body is not captured. The body longer
+// than the capture limit is cut and marked with "...(truncated)"
+func (w *responseWriter) Body() string {
+	if w.body == nil {
+		return ""
+	}
+	if w.bodyTruncated {
+		return w.body.String() + truncatedMessage
+	}
+	return w.body.String()
+}
+
+// BodySize is to get the written response body size in bytes
+func (w *responseWriter) BodySize() int {
+	return w.size
+}
+
+// logResponse is to get the captured response to be logged
+func (w *responseWriter) logResponse() *LogResponse {
+	if w == nil {
+		return &LogResponse{}
+	}
+
+	// the handler has returned, the whole body is written
+	if w.digest != nil {
+		w.digest.finish()
+	}
+	if w.tail != nil {
+		w.tail.finish()
+	}
+
+	return &LogResponse{
+		Status:            w.Status,
+		Header:            w.Header(),
+		Body:              w.Body(),
+		BodyTruncated:     w.bodyTruncated,
+		BodySize:          w.BodySize(),
+		TimedOut:          w.TimedOut,
+		Streaming:         w.Streaming,
+		PanicStack:        w.PanicStack,
+		DoubleWriteHeader: w.DoubleWriteHeader,
+		Fields:            w.fields,
+		bodyDigest:        w.digest,
+		bodyTail:          w.tail,
+	}
+}
+
+// release is to return the body buffer to the pool, the writer must not be used afterwards
+func (w *responseWriter) release() {
+	if w == nil || w.body == nil {
+		return
+	}
+	if w.body.Cap() <= maxPooledBodyBufferSize {
+		w.body.Reset()
+		bodyBufferPool.Put(w.body)
+	}
+	w.body = nil
+}
@@ -0,0 +1,188 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestResponseWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	writer := newResponseWriter(recorder, defaultMaxBodyCaptureBytes, false, 0)
+	writer.WriteHeader(http.StatusCreated)
+	writer.Write([]byte("Hello "))
+	writer.Write([]byte("World"))
+
+	assert.Equal(t, http.StatusCreated, writer.Status)
+	assert.Equal(t, "Hello World", writer.Body())
+	assert.Equal(t, 11, writer.BodySize())
+	assert.Equal(t, "Hello World", recorder.Body.String())
+
+	body := writer.Body()
+	writer.release()
+	assert.Equal(t, "Hello World", body)
+}
+
+func TestResponseWriterCaptureLimit(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	writer := newResponseWriter(recorder, 8, false, 0)
+	writer.Write([]byte("Hello "))
+	writer.Write([]byte("World"))
+	writer.Write([]byte("!"))
+
+	assert.Equal(t, "Hello Wo"+truncatedMessage, writer.Body())
+	assert.True(t, writer.logResponse().BodyTruncated)
+	assert.Equal(t, 12, writer.BodySize())
+	// the client still receives the whole body
+	assert.Equal(t, "Hello World!", recorder.Body.String())
+}
+
+var benchmarkResponseBody = bytes.Repeat([]byte("a"), 4096)
+
+func BenchmarkResponseWriterPooled(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		writer := newResponseWriter(httptest.NewRecorder(), defaultMaxBodyCaptureBytes, false, 0)
+		writer.Write(benchmarkResponseBody)
+		_ = writer.BodySize()
+		writer.release()
+	}
+}
+
+func BenchmarkResponseWriterUnpooled(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		writer := &responseWriter{ResponseWriter: httptest.NewRecorder(), body: new(bytes.Buffer)}
+		writer.Write(benchmarkResponseBody)
+		_ = writer.BodySize()
+	}
+}
+
+func TestStatusWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	writer := newStatusWriter(recorder)
+	writer.WriteHeader(http.StatusAccepted)
+	writer.Write([]byte("Hello World"))
+
+	assert.Equal(t, http.StatusAccepted, writer.Status)
+	assert.Equal(t, "", writer.Body())
+	assert.Equal(t, 11, writer.BodySize())
+	assert.Equal(t, "Hello World", recorder.Body.String())
+
+	writer.release()
+}
+
+func TestAddLogField(t *testing.T) {
+	for _, handlerTimeout := range []time.Duration{0, time.Second} {
+		logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+		middleware := NewIngressLogMiddleware(logger, &Config{HandlerTimeout: handlerTimeout})
+		handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			AddLogField(w, "tenant", "shopee")
+			AddLogField(w, FieldStatus, "overridden")
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+		assert.Equal(t, "shopee", hook.LastEntry().Data["tenant"])
+		assert.Equal(t, http.StatusOK, hook.LastEntry().Data[FieldStatus])
+	}
+
+	// no-op on the response writer not wrapped by the middleware
+	AddLogField(httptest.NewRecorder(), "tenant", "shopee")
+}
+
+func TestLogIngressDoubleWriteHeader(t *testing.T) {
+	tests := []struct {
+		name           string
+		handlerTimeout time.Duration
+		handler        http.HandlerFunc
+		expected       bool
+	}{
+		{
+			name: "single write header",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("created"))
+			},
+		},
+		{
+			name: "write header after write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expected: true,
+		},
+		{
+			name: "double write header",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expected: true,
+		},
+		{
+			name:           "double write header with handler timeout",
+			handlerTimeout: time.Second,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusOK)
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+			middleware := NewIngressLogMiddleware(logger, &Config{HandlerTimeout: tt.handlerTimeout})
+
+			recorder := httptest.NewRecorder()
+			middleware.Enforce(tt.handler).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+			doubleWriteHeader, _ := hook.LastEntry().Data[FieldDoubleWriteHeader].(bool)
+			assert.Equal(t, tt.expected, doubleWriteHeader)
+			// the logged status is the one the client receives
+			assert.Equal(t, recorder.Code, hook.LastEntry().Data[FieldStatus])
+		})
+	}
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	middleware := NewIngressLogMiddleware(logger)
+	server := httptest.NewServer(middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+	})))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	resp.Body.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, http.StatusSwitchingProtocols, hook.LastEntry().Data[FieldStatus])
+
+	_, _, err = newResponseWriter(httptest.NewRecorder(), defaultMaxBodyCaptureBytes, false, 0).Hijack()
+	assert.Equal(t, errHijackNotSupported, err)
+}
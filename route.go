@@ -0,0 +1,66 @@
+package httpmiddleware
+
+import (
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// routeTemplate is to derive the httprouter route pattern by substituting the param values in the path
+// back with their names, e.g: "/users/123" with id=123 becomes "/users/:id". The segments are matched
+// from the end of the path, so a static segment equal to a param value may be ambiguous, use
+// EnforceWithRoute when the exact pattern is required
+func routeTemplate(path string, ps httprouter.Params) string {
+	if len(ps) == 0 {
+		return path
+	}
+
+	var catchAll string
+	if last := ps[len(ps)-1]; strings.HasPrefix(last.Value, URLSeparator) && strings.HasSuffix(path, last.Value) {
+		// catch-all param always comes last and contains the rest of the path
+		path = strings.TrimSuffix(path, last.Value)
+		catchAll = URLSeparator + "*" + last.Key
+		ps = ps[:len(ps)-1]
+	}
+
+	segments := strings.Split(path, URLSeparator)
+	paramIdx := len(ps) - 1
+	for idx := len(segments) - 1; idx >= 0 && paramIdx >= 0; idx-- {
+		if segments[idx] == ps[paramIdx].Value {
+			segments[idx] = ":" + ps[paramIdx].Key
+			paramIdx--
+		}
+	}
+
+	return strings.Join(segments, URLSeparator) + catchAll
+}
+
+// routeParams is to convert the httprouter params into a map, nil when there is no param
+func routeParams(ps httprouter.Params) map[string]string {
+	if len(ps) == 0 {
+		return nil
+	}
+
+	params := make(map[string]string, len(ps))
+	for _, param := range ps {
+		params[param.Key] = param.Value
+	}
+
+	return params
+}
+
+// formatRouteParams is to copy the route params to be logged, the values of SensitiveRouteParams are redacted
+func (c *Config) formatRouteParams(params map[string]string) map[string]string {
+	loggedParams := make(map[string]string, len(params))
+	for key, value := range params {
+		loggedParams[key] = value
+		for _, sensitiveKey := range c.SensitiveRouteParams {
+			if strings.EqualFold(key, sensitiveKey) {
+				loggedParams[key] = wipedMessage
+				break
+			}
+		}
+	}
+
+	return loggedParams
+}
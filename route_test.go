@@ -0,0 +1,115 @@
+package httpmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/julienschmidt/httprouter"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestRouteTemplate(t *testing.T) {
+	tests := []struct {
+		path     string
+		params   httprouter.Params
+		expected string
+	}{
+		{
+			path:     "/hello",
+			expected: "/hello",
+		},
+		{
+			path:     "/users/12345/orders/98765",
+			params:   httprouter.Params{{Key: "id", Value: "12345"}, {Key: "orderId", Value: "98765"}},
+			expected: "/users/:id/orders/:orderId",
+		},
+		{
+			path:     "/users/5/orders/5",
+			params:   httprouter.Params{{Key: "id", Value: "5"}, {Key: "orderId", Value: "5"}},
+			expected: "/users/:id/orders/:orderId",
+		},
+		{
+			path:     "/users/users",
+			params:   httprouter.Params{{Key: "id", Value: "users"}},
+			expected: "/users/:id",
+		},
+		{
+			path:     "/src/v1/static/js/app.js",
+			params:   httprouter.Params{{Key: "version", Value: "v1"}, {Key: "filepath", Value: "/js/app.js"}},
+			expected: "/src/:version/static/*filepath",
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, routeTemplate(tt.path, tt.params), tt.path)
+	}
+}
+
+func TestLogIngressMessageRoute(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger)
+
+	handle := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	router := httprouter.New()
+	router.GET("/users/:id/orders/:orderId", logIngressMiddleware.EnforceWithParams(handle))
+	router.GET("/items/:id", logIngressMiddleware.EnforceWithRoute("/items/:id", handle))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/12345/orders/98765", nil))
+	assert.Equal(t, "/users/:id/orders/:orderId", hook.LastEntry().Data[FieldRoute])
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/items", nil))
+	assert.Equal(t, "/items/:id", hook.LastEntry().Data[FieldRoute])
+}
+
+func TestLogIngressMessageRouteNameExtractor(t *testing.T) {
+	type contextKey string
+	routeKey := contextKey("route")
+
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{
+		RouteNameExtractor: func(r *http.Request) string {
+			name, _ := r.Context().Value(routeKey).(string)
+			return name
+		},
+	})
+
+	handler := logIngressMiddleware.Enforce(http.HandlerFunc(echoHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), routeKey, "get-user"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "get-user", hook.LastEntry().Data[FieldRoute])
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	_, ok := hook.LastEntry().Data[FieldRoute]
+	assert.False(t, ok)
+}
+
+func TestLogIngressMessageRouteParams(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{
+		LogRouteParams:       true,
+		SensitiveRouteParams: []string{"token"},
+	})
+
+	handle := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	router := httprouter.New()
+	router.GET("/users/:id/invites/:token", logIngressMiddleware.EnforceWithParams(handle))
+	router.GET("/health", logIngressMiddleware.EnforceWithParams(handle))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123/invites/secret", nil))
+	assert.Equal(t, map[string]string{"id": "123", "token": wipedMessage}, hook.LastEntry().Data[FieldRouteParams])
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	_, ok := hook.LastEntry().Data[FieldRouteParams]
+	assert.False(t, ok)
+}
@@ -0,0 +1,41 @@
+package httpmiddleware
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+)
+
+// isSuccessStatus is to check whether the response status is in the 2xx range
+func isSuccessStatus(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices
+}
+
+// isSuccessSampled is to decide whether the successful request is logged based on SuccessSampleRate,
+// non-2xx responses and AlwaysLogStatuses are always logged
+func (c *Config) isSuccessSampled(contextID string, status int) bool {
+	if !isSuccessStatus(status) || c.IsAlwaysLoggedStatus(status) {
+		return true
+	}
+
+	return isSampled(contextID, c.SuccessSampleRate)
+}
+
+// isSampled is to make a deterministic sampling decision of the context id, so every service
+// logging the same request makes the same decision. Rate outside (0, 1) samples everything
+func isSampled(contextID string, rate float64) bool {
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(contextID))
+
+	return float64(hash.Sum32())/math.MaxUint32 < rate
+}
+
+// isBodySampled is to decide whether the request/response bodies are logged based on BodySampleRate,
+// the bodies of AlwaysLogStatuses are always logged
+func (c *Config) isBodySampled(contextID string, status int) bool {
+	return c.IsAlwaysLoggedStatus(status) || isSampled(contextID, c.BodySampleRate)
+}
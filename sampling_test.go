@@ -0,0 +1,92 @@
+package httpmiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestIsSampled(t *testing.T) {
+	assert.True(t, isSampled("context-id", 0))
+	assert.True(t, isSampled("context-id", 1))
+
+	sampled := 0
+	for idx := 0; idx < 10000; idx++ {
+		contextID := fmt.Sprintf("context-id-%d", idx)
+		if isSampled(contextID, 0.25) {
+			sampled++
+		}
+
+		// the decision is deterministic for the same context id
+		assert.Equal(t, isSampled(contextID, 0.25), isSampled(contextID, 0.25))
+	}
+
+	assert.True(t, sampled > 2000 && sampled < 3000, sampled)
+}
+
+func TestConfigIsSuccessSampled(t *testing.T) {
+	config := NewConfig(&Config{SuccessSampleRate: 0.0001})
+
+	notSampled := ""
+	for idx := 0; idx < 100; idx++ {
+		contextID := fmt.Sprintf("context-id-%d", idx)
+		if !isSampled(contextID, config.SuccessSampleRate) {
+			notSampled = contextID
+			break
+		}
+	}
+
+	assert.False(t, config.isSuccessSampled(notSampled, http.StatusOK))
+	assert.False(t, config.isSuccessSampled(notSampled, http.StatusCreated))
+	assert.True(t, config.isSuccessSampled(notSampled, http.StatusNotFound))
+	assert.True(t, config.isSuccessSampled(notSampled, http.StatusInternalServerError))
+
+	// 0 is unset, every request is logged the same as 1.0
+	assert.True(t, NewConfig(&Config{}).isSuccessSampled(notSampled, http.StatusOK))
+	assert.True(t, NewConfig(&Config{SuccessSampleRate: 1}).isSuccessSampled(notSampled, http.StatusOK))
+}
+
+func TestConfigBuildLogMessageBodySampleRate(t *testing.T) {
+	config := NewConfig(&Config{BodySampleRate: 0.5})
+	request := &LogRequest{Method: http.MethodPost, Path: "/users", Body: "request"}
+	response := &LogResponse{Status: http.StatusOK, Header: http.Header{}, Body: "response"}
+
+	sampled, wiped := 0, 0
+	for idx := 0; idx < 100; idx++ {
+		contextID := fmt.Sprintf("context-id-%d", idx)
+		ctx := context.WithValue(context.Background(), log.ContextDataMapKey, map[string]string{log.ContextIdKey: contextID})
+
+		msg := config.BuildLogMessage(ctx, request, response, 0, time.Now())
+		assert.Equal(t, http.StatusOK, msg.ResponseCode)
+		if isSampled(contextID, config.BodySampleRate) {
+			sampled++
+			assert.Equal(t, "request", msg.ReqBody)
+			assert.Equal(t, "response", msg.ResponseBody)
+		} else {
+			wiped++
+			assert.Equal(t, wipedMessage, msg.ReqBody)
+			assert.Equal(t, wipedMessage, msg.ResponseBody)
+		}
+	}
+	assert.True(t, sampled > 0)
+	assert.True(t, wiped > 0)
+}
+
+func TestConfigAlwaysLogStatuses(t *testing.T) {
+	config := NewConfig(&Config{SuccessSampleRate: 0.0001, BodySampleRate: 0.0001})
+	assert.True(t, config.IsAlwaysLoggedStatus(http.StatusBadGateway))
+	assert.False(t, config.IsAlwaysLoggedStatus(http.StatusOK))
+	assert.True(t, config.isBodySampled("context-id", http.StatusInternalServerError))
+	assert.False(t, config.isBodySampled("context-id", http.StatusNotFound))
+
+	config = NewConfig(&Config{SuccessSampleRate: 0.0001, BodySampleRate: 0.0001, AlwaysLogStatuses: []int{http.StatusAccepted}})
+	assert.True(t, config.isSuccessSampled("context-id", http.StatusAccepted))
+	assert.True(t, config.isBodySampled("context-id", http.StatusAccepted))
+	assert.False(t, config.isSuccessSampled("context-id", http.StatusOK))
+	assert.False(t, config.isBodySampled("context-id", http.StatusInternalServerError))
+}
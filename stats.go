@@ -0,0 +1,45 @@
+package httpmiddleware
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	statsKeyRequestsTotal   = "requests_total"
+	statsKeyRequests2xx     = "requests_2xx"
+	statsKeyRequests3xx     = "requests_3xx"
+	statsKeyRequests4xx     = "requests_4xx"
+	statsKeyRequests5xx     = "requests_5xx"
+	statsKeyDurationMsTotal = "duration_ms_total"
+)
+
+// requestStats are the request counters of the middleware, updated atomically. It must be the first field
+// of its parent struct, so the 64-bit counters are aligned on 32-bit platforms
+type requestStats struct {
+	total           int64
+	byStatusClass   [4]int64 // 2xx, 3xx, 4xx, 5xx
+	durationMsTotal int64
+}
+
+// record is to count the handled request by its status class and add its duration
+func (s *requestStats) record(status int, elapsedTime time.Duration) {
+	atomic.AddInt64(&s.total, 1)
+	atomic.AddInt64(&s.durationMsTotal, elapsedTime.Milliseconds())
+	if class := status/100 - 2; class >= 0 && class < len(s.byStatusClass) {
+		atomic.AddInt64(&s.byStatusClass[class], 1)
+	}
+}
+
+// Stats is to get the counters of the handled requests by status class and their total duration, e.g: to be
+// published with expvar.Publish("ingress", expvar.Func(func() interface{} { return ingressLog.Stats() }))
+func (i *IngressLog) Stats() map[string]int64 {
+	return map[string]int64{
+		statsKeyRequestsTotal:   atomic.LoadInt64(&i.stats.total),
+		statsKeyRequests2xx:     atomic.LoadInt64(&i.stats.byStatusClass[0]),
+		statsKeyRequests3xx:     atomic.LoadInt64(&i.stats.byStatusClass[1]),
+		statsKeyRequests4xx:     atomic.LoadInt64(&i.stats.byStatusClass[2]),
+		statsKeyRequests5xx:     atomic.LoadInt64(&i.stats.byStatusClass[3]),
+		statsKeyDurationMsTotal: atomic.LoadInt64(&i.stats.durationMsTotal),
+	}
+}
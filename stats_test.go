@@ -0,0 +1,45 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestIngressLogStats(t *testing.T) {
+	middleware := NewIngressLogMiddleware(log.NewLogger("log-ingress-middleware"), &Config{DisableIngressLog: true})
+	handler := middleware.Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+		case "/not-found":
+			w.WriteHeader(http.StatusNotFound)
+		case "/error":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+
+	for _, path := range []string{"/created", "/created", "/not-found", "/error"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	stats := middleware.Stats()
+	assert.Equal(t, int64(4), stats[statsKeyRequestsTotal])
+	assert.Equal(t, int64(2), stats[statsKeyRequests2xx])
+	assert.Equal(t, int64(0), stats[statsKeyRequests3xx])
+	assert.Equal(t, int64(1), stats[statsKeyRequests4xx])
+	assert.Equal(t, int64(1), stats[statsKeyRequests5xx])
+	assert.True(t, stats[statsKeyDurationMsTotal] >= 0)
+}
+
+func BenchmarkRequestStatsRecord(b *testing.B) {
+	var stats requestStats
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		stats.record(http.StatusOK, 0)
+	}
+}
@@ -0,0 +1,131 @@
+package httpmiddleware
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// bodyTail keeps the last n bytes of the body as the body streams through, so the tail of the body longer than
+// the capture limit is logged with HeadTailBytes without buffering the whole body
+type bodyTail struct {
+	mu       sync.Mutex // the request body may still be read by the timed out handler
+	n        int
+	buf      []byte
+	size     int
+	complete bool // true: the whole body is seen
+}
+
+func newBodyTail(n int) *bodyTail {
+	return &bodyTail{n: n, buf: make([]byte, 0, 2*n)}
+}
+
+func (t *bodyTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.size += len(p)
+	if len(p) >= t.n {
+		t.buf = append(t.buf[:0], p[len(p)-t.n:]...)
+		return len(p), nil
+	}
+
+	if len(t.buf)+len(p) > cap(t.buf) {
+		// keep only the last n bytes before appending, so the buffer never grows beyond 2n
+		t.buf = t.buf[:copy(t.buf, t.buf[len(t.buf)-t.n:])]
+	}
+	t.buf = append(t.buf, p...)
+	return len(p), nil
+}
+
+// finish is to mark the whole body as seen
+func (t *bodyTail) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.complete = true
+}
+
+// tail is to get the last n bytes and the size of the body, ok is false when the body is not completely seen,
+// e.g: the handler doesn't read the request body to the end
+func (t *bodyTail) tail() (tail string, size int, ok bool) {
+	if t == nil {
+		return "", 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.complete {
+		return "", 0, false
+	}
+
+	tail = string(t.buf)
+	if len(tail) > t.n {
+		tail = tail[len(tail)-t.n:]
+	}
+	return tail, t.size, true
+}
+
+// tailBody is to keep the last n bytes of the body as it is read, e.g: by the handler
+func tailBody(body *io.ReadCloser, n int) *bodyTail {
+	tail := newBodyTail(n)
+	*body = &tailReader{ReadCloser: *body, tail: tail}
+	return tail
+}
+
+// tailReader is a body keeping the last bytes read from it
+type tailReader struct {
+	io.ReadCloser
+	tail *bodyTail
+}
+
+func (r *tailReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.tail.Write(p[:n])
+	if err == io.EOF {
+		r.tail.finish()
+	}
+	return n, err
+}
+
+// formatHeadTailBody is to log the first and last HeadTailBytes of the body longer than the capture limit, e.g:
+// "[{...[1048580 bytes omitted]...}]". The head is taken from the captured body and the tail from the bytes kept
+// as the body streams through, ok is false when the body is not truncated or its tail is unknown
+func (c *Config) formatHeadTailBody(contentType string, body string, bodyTail *bodyTail) (string, bool) {
+	if c.HeadTailBytes <= 0 || !strings.HasSuffix(body, truncatedMessage) {
+		return "", false
+	}
+
+	tail, size, ok := bodyTail.tail()
+	if !ok {
+		return "", false
+	}
+
+	if !c.IsLoggableContentType(contentType) {
+		return wipedMessage, true
+	}
+	if c.isMaskedBody(contentType) {
+		// neither the head nor the tail can be parsed to be masked
+		return wipedMessage, true
+	}
+
+	head := strings.TrimSuffix(body, truncatedMessage)
+	if len(head) > c.HeadTailBytes {
+		head = head[:c.HeadTailBytes]
+	}
+
+	omitted := fmt.Sprintf(omittedMessage, size-len(head)-len(tail))
+	return c.redactCutDigits(c.redactBodyPart(contentType, head), false, true) + omitted +
+		c.redactCutDigits(c.redactBodyPart(contentType, tail), true, false), true
+}
+
+// redactBodyPart is to apply BodyRedactor and RedactPatterns to the part of the body
+func (c *Config) redactBodyPart(contentType string, part string) string {
+	if c.BodyRedactor != nil {
+		part = string(c.BodyRedactor(contentType, []byte(part)))
+	}
+
+	return c.redactBody(part)
+}
@@ -0,0 +1,77 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestBodyTail(t *testing.T) {
+	tail := newBodyTail(5)
+	for _, part := range []string{"HE", "AD_", "xxxxxxx", "x", "_T", "AIL"} {
+		tail.Write([]byte(part))
+	}
+
+	_, _, ok := tail.tail()
+	assert.False(t, ok)
+
+	tail.finish()
+	body, size, ok := tail.tail()
+	assert.True(t, ok)
+	assert.Equal(t, "_TAIL", body)
+	assert.Equal(t, 18, size)
+}
+
+func TestLogIngressHeadTailBytes(t *testing.T) {
+	body := "HEAD_" + strings.Repeat("x", 30) + "_TAIL"
+
+	tests := []struct {
+		name         string
+		maxBodyBytes int
+		handler      http.HandlerFunc
+		expectedReq  string
+		expectedRsp  string
+	}{
+		{
+			name:        "body within the capture limit",
+			handler:     echoHandler,
+			expectedReq: "HEAD_...[30 bytes omitted]..._TAIL",
+			expectedRsp: "HEAD_...[30 bytes omitted]..._TAIL",
+		},
+		{
+			name:         "body longer than the capture limit",
+			maxBodyBytes: 20,
+			handler:      echoHandler,
+			expectedReq:  "HEAD_...[30 bytes omitted]..._TAIL",
+			expectedRsp:  "HEAD_...[30 bytes omitted]..._TAIL",
+		},
+		{
+			name:         "request body not read to the end",
+			maxBodyBytes: 20,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(body))
+			},
+			expectedReq: body[:20] + truncatedMessage,
+			expectedRsp: "HEAD_...[30 bytes omitted]..._TAIL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+			middleware := NewIngressLogMiddleware(logger, &Config{MaxBodyBytes: tt.maxBodyBytes, HeadTailBytes: 5})
+
+			recorder := httptest.NewRecorder()
+			middleware.Enforce(tt.handler).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body)))
+
+			assert.Equal(t, body, recorder.Body.String())
+			assert.Equal(t, tt.expectedReq, hook.LastEntry().Data[FieldReqBody])
+			assert.Equal(t, tt.expectedRsp, hook.LastEntry().Data[FieldResponseBody])
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// noHandlerTimeoutKey is the request context key of WithoutHandlerTimeout
+type noHandlerTimeoutKey struct{}
+
+// WithoutHandlerTimeout is to opt the request out of HandlerTimeout, the handler always runs on the serving
+// goroutine until it returns, e.g: for the framework adapter whose context is reused once the middleware returns
+func WithoutHandlerTimeout(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), noHandlerTimeoutKey{}, true))
+}
+
+// timeoutWriter buffers the handler response, so it can be discarded when the handler times out
+type timeoutWriter struct {
+	parent      *responseWriter
+	mu          sync.Mutex
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.wroteHeader && !w.timedOut {
+		w.parent.DoubleWriteHeader = true
+	}
+	w.writeHeaderLocked(code)
+}
+
+func (w *timeoutWriter) Write(body []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	w.writeHeaderLocked(http.StatusOK)
+	return w.body.Write(body)
+}
+
+// AddLogField is to add the field to the ingress log, the field added after the timeout is dropped
+func (w *timeoutWriter) AddLogField(key string, val interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return
+	}
+
+	w.parent.AddLogField(key, val)
+}
+
+func (w *timeoutWriter) writeHeaderLocked(code int) {
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+
+	w.status = code
+	w.wroteHeader = true
+}
+
+// serveWithTimeout is to call the 'next' handler with HandlerTimeout deadline in the request context.
+// The handler response is written when it finishes in time, otherwise 503 is written and the late
+// handler writes fail with http.ErrHandlerTimeout. The handler panic is re-raised in the caller goroutine
+func (i *IngressLog) serveWithTimeout(config *Config, w *responseWriter, r *http.Request, next http.HandlerFunc) {
+	ctx, cancel := context.WithTimeout(r.Context(), config.HandlerTimeout)
+	defer cancel()
+
+	tw := &timeoutWriter{parent: w, header: make(http.Header)}
+	done := make(chan struct{})
+	panicChan := make(chan interface{}, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicChan <- p
+			}
+		}()
+
+		next(tw, r.WithContext(ctx))
+		close(done)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+
+		for key, values := range tw.header {
+			w.Header()[key] = values
+		}
+		if tw.wroteHeader {
+			w.WriteHeader(tw.status)
+		}
+		w.Write(tw.body.Bytes())
+	case <-ctx.Done():
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+
+		tw.timedOut = true
+		w.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(http.StatusText(http.StatusServiceUnavailable)))
+	}
+}
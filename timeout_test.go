@@ -0,0 +1,75 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestLogIngressMessageHandlerTimeout(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{HandlerTimeout: 50 * time.Millisecond})
+
+	hanging := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		w.Write([]byte("too late"))
+	})
+
+	recorder := httptest.NewRecorder()
+	logIngressMiddleware.Enforce(hanging).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/hang", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, hook.LastEntry().Data[FieldStatus])
+	assert.Equal(t, true, hook.LastEntry().Data[FieldTimedOut])
+
+	recorder = httptest.NewRecorder()
+	logIngressMiddleware.Enforce(http.HandlerFunc(statusHandler)).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status?code=201", nil))
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"code":201}`, recorder.Body.String())
+	assert.Equal(t, `{"code":201}`, hook.LastEntry().Data[FieldResponseBody])
+	_, ok := hook.LastEntry().Data[FieldTimedOut]
+	assert.False(t, ok)
+}
+
+func TestLogIngressMessageHandlerTimeoutPanic(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{HandlerTimeout: time.Second})
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("unexpected")
+	})
+
+	recorder := httptest.NewRecorder()
+	logIngressMiddleware.Enforce(panicking).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, http.StatusInternalServerError, hook.LastEntry().Data[FieldStatus])
+}
+
+func TestLogIngressWithoutHandlerTimeout(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	logIngressMiddleware := NewIngressLogMiddleware(logger, &Config{HandlerTimeout: 10 * time.Millisecond})
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	recorder := httptest.NewRecorder()
+	req := WithoutHandlerTimeout(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	logIngressMiddleware.Enforce(slow).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "done", recorder.Body.String())
+	_, ok := hook.LastEntry().Data[FieldTimedOut]
+	assert.False(t, ok)
+}
@@ -0,0 +1,22 @@
+package httpmiddleware
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// tlsVersionName is to get the name of the TLS version, e.g: "TLS 1.3"
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
@@ -0,0 +1,39 @@
+package httpmiddleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/c2fo/testify/assert"
+	"github.com/muhammad-fakhri/log"
+)
+
+func TestLogIngressTLSInfo(t *testing.T) {
+	logger, hook := log.NewLoggerWithTestHook("log-ingress-middleware")
+	mockServer := getMockServerWithConfig(logger, &Config{LogTLSInfo: true})
+	defer mockServer.Close()
+
+	_, err := http.Get(mockServer.URL + "/echo")
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	_, ok := hook.LastEntry().Data[FieldTLSVersion]
+	assert.False(t, ok)
+	_, ok = hook.LastEntry().Data[FieldTLSCipher]
+	assert.False(t, ok)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/echo", nil)
+	req.TLS = &tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	NewIngressLogMiddleware(logger, &Config{LogTLSInfo: true}).Enforce(http.HandlerFunc(echoHandler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "TLS 1.2", hook.LastEntry().Data[FieldTLSVersion])
+	assert.Equal(t, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", hook.LastEntry().Data[FieldTLSCipher])
+}
+
+func TestTLSVersionName(t *testing.T) {
+	assert.Equal(t, "TLS 1.3", tlsVersionName(tls.VersionTLS13))
+	assert.Equal(t, "0x0300", tlsVersionName(0x0300))
+}
@@ -0,0 +1,43 @@
+package httpmiddleware
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// appendTraceContext is to add the OpenTelemetry trace and span id of the request into the data map
+func appendTraceContext(ctx context.Context, dataMap map[string]interface{}) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return
+	}
+
+	dataMap[FieldTraceID] = spanContext.TraceID().String()
+	dataMap[FieldSpanID] = spanContext.SpanID().String()
+}
+
+// appendTraceParent is to add the trace and parent span id of the W3C traceparent header into the data map,
+// e.g: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". The trace id of the OpenTelemetry trace context
+// is not overridden, the invalid header is ignored
+func appendTraceParent(header string, dataMap map[string]interface{}) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return
+	}
+
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return
+	}
+	parentSpanID, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		return
+	}
+
+	if _, ok := dataMap[FieldTraceID]; !ok {
+		dataMap[FieldTraceID] = traceID.String()
+	}
+	dataMap[FieldParentSpanID] = parentSpanID.String()
+}
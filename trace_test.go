@@ -0,0 +1,51 @@
+package httpmiddleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/c2fo/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAppendTraceContext(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	dataMap := make(map[string]interface{})
+	appendTraceContext(ctx, dataMap)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", dataMap[FieldTraceID])
+	assert.Equal(t, "00f067aa0ba902b7", dataMap[FieldSpanID])
+
+	dataMap = make(map[string]interface{})
+	appendTraceContext(context.Background(), dataMap)
+	assert.Equal(t, 0, len(dataMap))
+}
+
+func TestAppendTraceParent(t *testing.T) {
+	dataMap := make(map[string]interface{})
+	appendTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", dataMap)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", dataMap[FieldTraceID])
+	assert.Equal(t, "00f067aa0ba902b7", dataMap[FieldParentSpanID])
+
+	// the trace id of the trace context is kept
+	dataMap = map[string]interface{}{FieldTraceID: "0af7651916cd43dd8448eb211c80319c"}
+	appendTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", dataMap)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", dataMap[FieldTraceID])
+
+	for _, header := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		dataMap = make(map[string]interface{})
+		appendTraceParent(header, dataMap)
+		assert.Equal(t, 0, len(dataMap), header)
+	}
+}
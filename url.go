@@ -0,0 +1,92 @@
+package httpmiddleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// appendURLFields is to add the method, path and the combined url fields into the data map
+func (c *Config) appendURLFields(dataMap map[string]interface{}, request *LogRequest) {
+	dataMap[FieldMethod] = request.Method
+	dataMap[FieldPath] = c.sanitizeURL(request.Path)
+
+	if !c.DisableCombinedURL {
+		dataMap[FieldURL] = c.formatURL(request)
+	}
+}
+
+// formatURL is to build the logged "METHOD URL" value, the query string is omitted when
+// the query params are logged separately
+func (c *Config) formatURL(request *LogRequest) string {
+	if c.LogQueryParams {
+		return fmt.Sprintf("%s %s", request.Method, c.sanitizeURL(request.Path))
+	}
+
+	return fmt.Sprintf("%s %s", request.Method, c.sanitizeURL(request.URL))
+}
+
+// sanitizeURL is to apply URLSanitizer to the path of the logged url, the query string is kept as it is
+func (c *Config) sanitizeURL(rawURL string) string {
+	if c.URLSanitizer == nil {
+		return rawURL
+	}
+
+	if idx := strings.IndexByte(rawURL, '?'); idx >= 0 {
+		return c.URLSanitizer(rawURL[:idx]) + rawURL[idx:]
+	}
+
+	return c.URLSanitizer(rawURL)
+}
+
+// redactQueryString is to redact the values of SensitiveQueryKeys in the query string of the url, e.g: for the
+// access log line. The order and the encoding of the other query params are kept
+func (c *Config) redactQueryString(rawURL string) string {
+	idx := strings.IndexByte(rawURL, '?')
+	if idx < 0 || len(c.SensitiveQueryKeys) == 0 {
+		return rawURL
+	}
+
+	params := strings.Split(rawURL[idx+1:], "&")
+	for i, param := range params {
+		key := param
+		if eq := strings.IndexByte(param, '='); eq >= 0 {
+			key = param[:eq]
+		}
+
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if c.isSensitiveQueryKey(name) {
+			params[i] = key + "=" + wipedMessage
+		}
+	}
+
+	return rawURL[:idx+1] + strings.Join(params, "&")
+}
+
+// formatQueryParams is to copy the query params with the sensitive values redacted
+func (c *Config) formatQueryParams(query url.Values) url.Values {
+	loggedQuery := make(url.Values, len(query))
+	for key, values := range query {
+		if c.isSensitiveQueryKey(key) {
+			loggedQuery[key] = []string{wipedMessage}
+			continue
+		}
+
+		loggedQuery[key] = append([]string{}, values...)
+	}
+
+	return loggedQuery
+}
+
+func (c *Config) isSensitiveQueryKey(key string) bool {
+	for _, sensitiveKey := range c.SensitiveQueryKeys {
+		if strings.EqualFold(key, sensitiveKey) {
+			return true
+		}
+	}
+
+	return false
+}